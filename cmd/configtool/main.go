@@ -0,0 +1,134 @@
+// Command configtool manages the config file of the server:
+//
+//	configtool init [-o config.jsonc] [-force]           write the documented defaults
+//	configtool validate [-config path] [-profile name]   check the config, exit 1 on errors
+//	configtool print [-config path] [-profile name]      print the effective settings and their sources
+//	configtool diff [-config path] [-profile name]       print the settings of the files that are not defaults
+//
+// Without -config, the config file is searched in config.DefaultSearchPaths.
+// validate and print apply the environment and the setting flags given
+// after "--", "configtool print -- --listen-port=9090". Secrets are
+// redacted.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/yikailee/golang/config"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage: configtool <command> [flags]
+
+commands:
+  init      write a config file holding the documented defaults
+  validate  check the config, exit 1 on errors
+  print     print the effective settings and their sources
+  diff      print the settings of the config file that are not defaults
+`
+
+// run runs the command of args and returns the exit code.
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("configtool "+cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+
+	var err error
+	switch cmd {
+	case "init":
+		output := fs.String("o", "config.jsonc", "config file to write, its extension selects the format, .json has no docs")
+		force := fs.Bool("force", false, "overwrite an existing file")
+		if fs.Parse(args) != nil {
+			return 2
+		}
+		err = initFile(*output, *force, stdout, stderr)
+	case "validate", "print", "diff":
+		path := fs.String("config", "", "config file, searched in the default search paths when empty")
+		profile := fs.String("profile", "", "profile of the overlay file, <name>.<profile>.json for <name>.json, <env-prefix>_PROFILE when empty")
+		envPrefix := fs.String("env-prefix", "", "prefix of the environment variables, APP when empty")
+		if fs.Parse(args) != nil {
+			return 2
+		}
+		opts := config.Options{Path: *path, Profile: *profile, EnvPrefix: *envPrefix, Args: fs.Args()}
+		switch cmd {
+		case "validate":
+			err = validate(opts, stdout)
+		case "print":
+			err = printConfig(opts, stdout)
+		default:
+			err = diff(opts, stdout)
+		}
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "configtool: unknown command %q\n%s", cmd, usage)
+		return 2
+	}
+
+	if err != nil {
+		var errs config.ValidationErrors
+		if errors.As(err, &errs) {
+			for _, e := range errs {
+				fmt.Fprintln(stderr, e)
+			}
+		} else {
+			fmt.Fprintln(stderr, "configtool:", err)
+		}
+		return 1
+	}
+	return 0
+}
+
+func initFile(path string, force bool, stdout, stderr io.Writer) error {
+	if err := config.WriteDefaultFile(path, force); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %s\n", path)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		fmt.Fprintln(stderr, "configtool: json files can not hold the docs of the settings, use .jsonc or .yaml")
+	}
+	return nil
+}
+
+func validate(opts config.Options, stdout io.Writer) error {
+	if _, err := config.Load(opts); err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, "config is valid")
+	return nil
+}
+
+func printConfig(opts config.Options, stdout io.Writer) error {
+	_, settings, err := config.LoadSettings(opts)
+	if err != nil {
+		return err
+	}
+	return config.PrintSettings(stdout, settings)
+}
+
+func diff(opts config.Options, stdout io.Writer) error {
+	diffs, err := config.DiffDefaults(opts)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	for _, d := range diffs {
+		fmt.Fprintf(tw, "%s\t%v\t->\t%v\n", d.Key, d.Default, d.Value)
+	}
+	return tw.Flush()
+}
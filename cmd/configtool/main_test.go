@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	invalid := filepath.Join(dir, "invalid.json")
+	existing := filepath.Join(dir, "existing.yaml")
+	ini := filepath.Join(dir, "config.ini")
+	for path, content := range map[string]string{
+		ini:                                   "listen_port = 9090\n",
+		valid:                                 `{"listenPort": 9090, "log": {"level": "debug"}}`,
+		invalid:                               `{"listenPort": 0, "log": {"levl": "debug"}}`,
+		existing:                              "listenPort: 9090\n",
+		filepath.Join(dir, "valid.prod.json"): `{"listenPort": 9091}`,
+	} {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testData := []struct {
+		name   string
+		args   []string
+		code   int
+		stdout []string
+		stderr []string
+	}{
+		{
+			name:   "no command",
+			code:   2,
+			stderr: []string{"usage: configtool"},
+		},
+		{
+			name:   "unknown command",
+			args:   []string{"check"},
+			code:   2,
+			stderr: []string{`unknown command "check"`},
+		},
+		{
+			name:   "init",
+			args:   []string{"init", "-o", filepath.Join(dir, "config.yaml")},
+			stdout: []string{"wrote " + filepath.Join(dir, "config.yaml")},
+		},
+		{
+			name:   "init json",
+			args:   []string{"init", "-o", filepath.Join(dir, "config.json")},
+			stdout: []string{"wrote " + filepath.Join(dir, "config.json")},
+			stderr: []string{"json files can not hold the docs"},
+		},
+		{
+			name:   "init existing file",
+			args:   []string{"init", "-o", existing},
+			code:   1,
+			stderr: []string{"file exists"},
+		},
+		{
+			name:   "init overwrite",
+			args:   []string{"init", "-o", existing, "-force"},
+			stdout: []string{"wrote " + existing},
+		},
+		{
+			name:   "init overwrite unknown format",
+			args:   []string{"init", "-o", ini, "-force"},
+			code:   1,
+			stderr: []string{`unknown format ".ini"`},
+		},
+		{
+			name:   "validate",
+			args:   []string{"validate", "-config", valid},
+			stdout: []string{"config is valid"},
+		},
+		{
+			name: "validate errors",
+			args: []string{"validate", "-config", invalid},
+			code: 1,
+			stderr: []string{
+				"log.levl: unknown key, did you mean \"level\"?\n",
+				"listenPort: must be at least 1\n",
+			},
+		},
+		{
+			name:   "validate flags",
+			args:   []string{"validate", "-config", valid, "--", "--listen-port=70000"},
+			code:   1,
+			stderr: []string{"listenPort: must be at most 65535"},
+		},
+		{
+			name: "print",
+			args: []string{"print", "-config", valid, "--", "--log.format=json"},
+			stdout: []string{
+				"listenPort ", " 9090 ", " file " + valid + "\n",
+				"log.format ", " json ", " flag --log.format\n",
+			},
+		},
+		{
+			name:   "profile",
+			args:   []string{"diff", "-config", valid, "-profile", "prod"},
+			stdout: []string{"listenPort  8080  ->  9091\nlog.level   info  ->  debug\n"},
+		},
+		{
+			name:   "diff",
+			args:   []string{"diff", "-config", valid},
+			stdout: []string{"listenPort  8080  ->  9090\nlog.level   info  ->  debug\n"},
+		},
+	}
+
+	for _, test := range testData {
+		var stdout, stderr bytes.Buffer
+		code := run(test.args, &stdout, &stderr)
+		assert.Equal(t, test.code, code, test.name)
+		for _, wanted := range test.stdout {
+			assert.True(t, strings.Contains(stdout.String(), wanted), test.name+": "+wanted)
+		}
+		for _, wanted := range test.stderr {
+			assert.True(t, strings.Contains(stderr.String(), wanted), test.name+": "+wanted)
+		}
+	}
+
+	b, err := ioutil.ReadFile(existing)
+	assert.Nil(t, err, "overwritten file")
+	assert.True(t, strings.Contains(string(b), "# port of the server\nlistenPort: 8080\n"), "overwritten file")
+	b, err = ioutil.ReadFile(ini)
+	assert.Nil(t, err, "kept file")
+	assert.Equal(t, "listen_port = 9090\n", string(b), "kept file")
+}
+
+func TestInitDefaultOutput(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, run([]string{"init"}, &stdout, &stderr), "init")
+	assert.Equal(t, "wrote config.jsonc\n", stdout.String(), "init")
+	assert.Empty(t, stderr.String(), "init")
+	b, err := ioutil.ReadFile("config.jsonc")
+	assert.Nil(t, err, "init")
+	assert.Contains(t, string(b), "// port of the server\n", "documented defaults")
+	assert.Contains(t, string(b), "// max size of a multipart request body in bytes", "documented defaults")
+}
+
+func TestRunSecrets(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "s3cret")
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(path, []byte(`{"database": {"password": "${env:DB_PASSWORD}"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cmd := range []string{"print", "diff"} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 0, run([]string{cmd, "-config", path}, &stdout, &stderr), cmd)
+		assert.Contains(t, stdout.String(), "database.password", cmd)
+		assert.Contains(t, stdout.String(), "[REDACTED]", cmd)
+		assert.NotContains(t, stdout.String(), "s3cret", cmd)
+	}
+}
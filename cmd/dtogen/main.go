@@ -0,0 +1,480 @@
+// Command dtogen generates reflection free Bind methods for DTO structs.
+//
+// It reads the dto, required and default tags and the strict marker field of
+// the named struct types in the current package and writes, for each type, a
+// Bind(r *http.Request, opts ...middlewares.Option) error method binding the
+// request like middlewares.BindRequestParams, plus the AlreadySet, MarkSet
+// and MarkAllUnset methods of dto.ValidRequestDTO backed by a
+// map[string]bool field of the struct. Use it with
+//
+//	//go:generate go run github.com/yikailee/golang/cmd/dtogen -type User,Order
+//
+// Only fields of the basic kinds and slices or arrays of them are supported,
+// DTOs with nested structs, maps, file uploads or context sources have to
+// use middlewares.BindRequestParams.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const middlewaresPath = "github.com/yikailee/golang/middlewares"
+
+var (
+	typeNames = flag.String("type", "", "comma separated list of DTO type names")
+	output    = flag.String("output", "", "output file name, default <type>_dto.go")
+	setField  = flag.String("setfield", "setItems", "name of the map[string]bool field tracking the set dto names")
+	tests     = flag.Bool("tests", false, "also read _test.go files and write a _test.go file")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("dtogen: ")
+	flag.Parse()
+	if *typeNames == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	dir := "."
+	if args := flag.Args(); len(args) > 0 {
+		dir = args[0]
+	}
+	names := strings.Split(*typeNames, ",")
+	src, err := generate(dir, names, *setField, *tests)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := *output
+	if out == "" {
+		out = strings.ToLower(names[0]) + "_dto.go"
+		if *tests {
+			out = strings.ToLower(names[0]) + "_dto_test.go"
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, out), src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// dtoField is a dto tagged field of a DTO struct.
+type dtoField struct {
+	field      string
+	name       string
+	source     string
+	required   bool
+	def        *string
+	typ        fieldType
+	collection string
+}
+
+// fieldType describes a supported field type: a basic kind, or a slice or
+// array of one when length is -1 or the array length.
+type fieldType struct {
+	expr   string
+	elem   string
+	slice  bool
+	length int
+}
+
+type dtoStruct struct {
+	name   string
+	fields []dtoField
+	strict bool
+}
+
+func generate(dir string, names []string, setField string, tests bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(fi os.FileInfo) bool {
+		return tests || !strings.HasSuffix(fi.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkgName string
+	specs := make(map[string]*ast.StructType)
+	for name, pkg := range pkgs {
+		if strings.HasSuffix(name, "_test") {
+			continue
+		}
+		pkgName = name
+		for _, f := range pkg.Files {
+			ast.Inspect(f, func(n ast.Node) bool {
+				if ts, ok := n.(*ast.TypeSpec); ok {
+					if st, ok := ts.Type.(*ast.StructType); ok {
+						specs[ts.Name.Name] = st
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	var structs []dtoStruct
+	for _, name := range names {
+		st, ok := specs[name]
+		if !ok {
+			return nil, fmt.Errorf("struct type %s not found in %s", name, dir)
+		}
+		s, err := parseStruct(name, st, setField)
+		if err != nil {
+			return nil, err
+		}
+		structs = append(structs, s)
+	}
+
+	g := &generator{qualifier: "middlewares.", setField: setField}
+	if pkgName == "middlewares" {
+		g.qualifier = ""
+	}
+	g.header(pkgName)
+	for _, s := range structs {
+		g.dto(s)
+	}
+	return format.Source(g.buf.Bytes())
+}
+
+func parseStruct(name string, st *ast.StructType, setField string) (s dtoStruct, err error) {
+	s.name = name
+	hasSetField := false
+	for _, f := range st.Fields.List {
+		var tag reflect.StructTag
+		if f.Tag != nil {
+			t, err := strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return s, err
+			}
+			tag = reflect.StructTag(t)
+		}
+		if len(f.Names) == 1 && f.Names[0].Name == setField {
+			if typeString(f.Type) != "map[string]bool" {
+				return s, fmt.Errorf("%s.%s must be a map[string]bool", name, setField)
+			}
+			hasSetField = true
+			continue
+		}
+		if len(f.Names) == 1 && f.Names[0].Name == "_" {
+			s.strict = s.strict || tag.Get("strict") == "true"
+			continue
+		}
+		dtoTag := tag.Get("dto")
+		if len(f.Names) == 0 {
+			if dtoTag != "" || isStructLike(f.Type) {
+				return s, fmt.Errorf("%s: embedded fields are not supported", name)
+			}
+			continue
+		}
+		if dtoTag == "" {
+			continue
+		}
+		if len(f.Names) > 1 {
+			return s, fmt.Errorf("%s: dto tags on fields declared together are not supported", name)
+		}
+
+		df := dtoField{field: f.Names[0].Name, required: tag.Get("required") != "false"}
+		df.name, df.source = dtoTag, ""
+		if i := strings.IndexByte(dtoTag, ','); i >= 0 {
+			df.name, df.source = dtoTag[:i], strings.TrimSpace(dtoTag[i+1:])
+		}
+		switch df.source {
+		case "", "path", "query", "form", "body", "header", "cookie":
+		default:
+			return s, fmt.Errorf("%s.%s: source %q is not supported", name, df.field, df.source)
+		}
+		if df.typ, err = parseFieldType(f.Type); err != nil {
+			return s, fmt.Errorf("%s.%s: %v", name, df.field, err)
+		}
+		if def, ok := tag.Lookup("default"); ok {
+			if err := checkDefault(df.typ, def); err != nil {
+				return s, fmt.Errorf("%s.%s: bad default %q: %v", name, df.field, def, err)
+			}
+			df.def = &def
+		}
+		if df.collection = tag.Get("collection"); df.collection != "" {
+			if !df.typ.slice && df.typ.length < 0 {
+				return s, fmt.Errorf("%s.%s: collection tag on a non slice field", name, df.field)
+			}
+			switch df.collection {
+			case "multi", "csv", "ssv", "pipes", "brackets", "indexed":
+			default:
+				return s, fmt.Errorf("%s.%s: unknown collection format %q", name, df.field, df.collection)
+			}
+		}
+		s.fields = append(s.fields, df)
+	}
+	if !hasSetField {
+		return s, fmt.Errorf("%s needs a %s map[string]bool field", name, setField)
+	}
+	return s, nil
+}
+
+func isStructLike(e ast.Expr) bool {
+	switch e := e.(type) {
+	case *ast.StarExpr:
+		return isStructLike(e.X)
+	case *ast.Ident, *ast.SelectorExpr, *ast.StructType:
+		return true
+	}
+	return false
+}
+
+func typeString(e ast.Expr) string {
+	var b bytes.Buffer
+	format.Node(&b, token.NewFileSet(), e)
+	return b.String()
+}
+
+var basicKinds = map[string]bool{
+	"string": true, "bool": true,
+	"int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"float32": true, "float64": true,
+}
+
+func parseFieldType(e ast.Expr) (fieldType, error) {
+	ft := fieldType{expr: typeString(e), length: -1}
+	switch e := e.(type) {
+	case *ast.Ident:
+		ft.elem = e.Name
+	case *ast.ArrayType:
+		elem, ok := e.Elt.(*ast.Ident)
+		if !ok {
+			return ft, fmt.Errorf("unsupported type %s", ft.expr)
+		}
+		ft.elem = elem.Name
+		if e.Len == nil {
+			ft.slice = true
+			break
+		}
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return ft, fmt.Errorf("unsupported array length in %s", ft.expr)
+		}
+		n, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return ft, err
+		}
+		ft.length = n
+	default:
+		return ft, fmt.Errorf("unsupported type %s", ft.expr)
+	}
+	if !basicKinds[ft.elem] {
+		return ft, fmt.Errorf("unsupported type %s", ft.expr)
+	}
+	return ft, nil
+}
+
+func checkDefault(ft fieldType, def string) error {
+	values := []string{def}
+	if ft.slice || ft.length >= 0 {
+		values = strings.Split(def, ",")
+		if len(values) < ft.length {
+			return errors.New("not enough values")
+		}
+	}
+	for _, v := range values {
+		var err error
+		switch ft.elem {
+		case "bool":
+			_, err = strconv.ParseBool(v)
+		case "float32", "float64":
+			_, err = strconv.ParseFloat(v, bitSize(ft.elem))
+		case "string":
+		default:
+			_, err = strconv.ParseInt(v, 10, bitSize(ft.elem))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bitSize returns the bit size the middlewares package parses a kind with,
+// 0 for the size of int.
+func bitSize(kind string) int {
+	switch kind {
+	case "int":
+		return 0
+	case "int8":
+		return 8
+	case "int16":
+		return 16
+	case "int32", "float32":
+		return 32
+	}
+	return 64
+}
+
+type generator struct {
+	buf       bytes.Buffer
+	qualifier string
+	setField  string
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+func (g *generator) header(pkgName string) {
+	g.printf("// Code generated by dtogen; DO NOT EDIT.\n\n")
+	g.printf("package %s\n\n", pkgName)
+	g.printf("import (\n\t\"net/http\"\n")
+	if g.qualifier != "" {
+		g.printf("\n\t%q\n", middlewaresPath)
+	}
+	g.printf(")\n")
+}
+
+func (g *generator) dto(s dtoStruct) {
+	q := g.qualifier
+	var bodyFields, params []string
+	requiresBody := false
+	for _, f := range s.fields {
+		param := f.name
+		if f.source != "" {
+			param += "," + f.source
+		}
+		params = append(params, strconv.Quote(param))
+		if f.source == "" || f.source == "body" {
+			bodyFields = append(bodyFields, strconv.Quote(f.name))
+		}
+		if f.source == "body" && f.required && f.def == nil {
+			requiresBody = true
+		}
+	}
+
+	g.printf("\nfunc (u *%s) AlreadySet(dtoName string) bool {\n", s.name)
+	g.printf("\t_, ok := u.%s[dtoName]\n\treturn ok\n}\n", g.setField)
+	g.printf("\nfunc (u *%s) MarkSet(dtoName string) {\n", s.name)
+	g.printf("\tif u.%s == nil {\n\t\tu.%[1]s = make(map[string]bool)\n\t}\n", g.setField)
+	g.printf("\tu.%s[dtoName] = true\n}\n", g.setField)
+	g.printf("\nfunc (u *%s) MarkAllUnset() {\n", s.name)
+	g.printf("\tu.%s = make(map[string]bool)\n}\n", g.setField)
+
+	g.printf("\n// Bind binds the params of r into u like %sBindRequestParams.\n", q)
+	g.printf("func (u *%s) Bind(r *http.Request, opts ...%sOption) error {\n", s.name, q)
+	g.printf("\tvalues, err := %sNewBindValues(r, []string{%s}, opts...)\n", q, strings.Join(bodyFields, ", "))
+	g.printf("\tif err != nil {\n\t\treturn err\n\t}\n")
+	if requiresBody {
+		g.printf("\tif err := values.RequireBody(); err != nil {\n\t\treturn err\n\t}\n")
+	}
+	if s.strict {
+		g.printf("\tvalues.Strict()\n")
+	}
+	g.printf("\tif err := values.CheckUnknownParams(%s); err != nil {\n\t\treturn err\n\t}\n", strings.Join(params, ", "))
+	for _, f := range s.fields {
+		g.bindField(f)
+	}
+	g.printf("\treturn nil\n}\n")
+
+	for _, f := range s.fields {
+		g.setter(s, f)
+	}
+}
+
+func (g *generator) bindField(f dtoField) {
+	q := g.qualifier
+	name := strconv.Quote(f.name)
+	setter := "dtoBind" + f.field
+	if f.collection != "" {
+		g.printf("\tif v, ok, err := values.LookupCollection(%s, %q, %q); err != nil {\n\t\treturn err\n", name, f.source, f.collection)
+	} else {
+		g.printf("\tif v, ok, err := values.Lookup(%s, %q); err != nil {\n\t\treturn err\n", name, f.source)
+	}
+	switch {
+	case f.def != nil:
+		var defs []string
+		values := []string{*f.def}
+		if f.typ.slice || f.typ.length >= 0 {
+			values = strings.Split(*f.def, ",")
+		}
+		for _, d := range values {
+			defs = append(defs, strconv.Quote(d))
+		}
+		g.printf("\t} else if !ok {\n")
+		g.printf("\t\tif err := u.%s(%sStringValues(%s)); err != nil {\n", setter, q, strings.Join(defs, ", "))
+		g.printf("\t\t\treturn %sFieldError(%s, err)\n\t\t}\n", q, name)
+	case f.required:
+		g.printf("\t} else if !ok {\n\t\treturn %sMissingParam(%s)\n", q, name)
+	}
+	switch {
+	case f.required:
+		g.printf("\t} else if err := u.%s(v); err != nil {\n", setter)
+		g.printf("\t\treturn %sFieldError(%s, err)\n", q, name)
+		g.printf("\t} else {\n\t\tu.MarkSet(%s)\n\t}\n", name)
+	case f.def != nil:
+		g.printf("\t} else if u.%s(v) == nil {\n\t\tu.MarkSet(%s)\n\t}\n", setter, name)
+	default:
+		g.printf("\t} else if ok && u.%s(v) == nil {\n\t\tu.MarkSet(%s)\n\t}\n", setter, name)
+	}
+}
+
+// convert returns the expression converting the BindValue v to kind, with
+// the type conversion to apply to its result.
+func convert(v, kind string) (expr, conv string) {
+	switch kind {
+	case "string":
+		return v + ".String()", ""
+	case "bool":
+		return v + ".Bool()", ""
+	case "float32", "float64":
+		return fmt.Sprintf("%s.Float(%d)", v, bitSize(kind)), kind
+	}
+	conv = kind
+	if kind == "int64" {
+		conv = ""
+	}
+	return fmt.Sprintf("%s.Int(%d)", v, bitSize(kind)), conv
+}
+
+func wrap(conv, x string) string {
+	if conv == "" || conv == "float64" {
+		return x
+	}
+	return conv + "(" + x + ")"
+}
+
+func (g *generator) setter(s dtoStruct, f dtoField) {
+	q := g.qualifier
+	g.printf("\nfunc (u *%s) dtoBind%s(v %sBindValue) error {\n", s.name, f.field, q)
+	if !f.typ.slice && f.typ.length < 0 {
+		expr, conv := convert("v", f.typ.elem)
+		g.printf("\tx, err := %s\n\tif err != nil {\n\t\treturn err\n\t}\n", expr)
+		g.printf("\tu.%s = %s\n\treturn nil\n}\n", f.field, wrap(conv, "x"))
+		return
+	}
+
+	zero := "nil"
+	if !f.typ.slice {
+		zero = f.typ.expr + "{}"
+	}
+	g.printf("\tif v.IsNull() {\n\t\tu.%s = %s\n\t\treturn nil\n\t}\n", f.field, zero)
+	g.printf("\tn, err := v.Len()\n\tif err != nil {\n\t\treturn err\n\t}\n")
+	if f.typ.slice {
+		g.printf("\ts := make(%s, n)\n", f.typ.expr)
+	} else {
+		g.printf("\tvar s %s\n", f.typ.expr)
+		g.printf("\tif n < len(s) {\n\t\treturn %sNotEnoughValues(len(s), n)\n\t}\n", q)
+	}
+	expr, conv := convert("v.Index(i)", f.typ.elem)
+	g.printf("\tfor i := range s {\n")
+	g.printf("\t\tx, err := %s\n\t\tif err != nil {\n\t\t\treturn err\n\t\t}\n", expr)
+	g.printf("\t\ts[i] = %s\n\t}\n", wrap(conv, "x"))
+	g.printf("\tu.%s = s\n\treturn nil\n}\n", f.field)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDTOSource(t *testing.T, src string) string {
+	dir, err := ioutil.TempDir("", "dtogen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "dto.go"), []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGenerateHappyPath(t *testing.T) {
+	dir := writeDTOSource(t, `package api
+
+type User struct {
+	ID       int64      `+"`"+`dto:"id,path"`+"`"+`
+	Tenant   string     `+"`"+`dto:"X-Tenant,header" required:"false"`+"`"+`
+	Page     int        `+"`"+`dto:"page" default:"1"`+"`"+`
+	Scores   [2]float32 `+"`"+`dto:"scores" required:"false" default:"1.5,2"`+"`"+`
+	IDs      []int64    `+"`"+`dto:"ids,query" required:"false" collection:"csv"`+"`"+`
+	internal string
+	setItems map[string]bool
+}
+`)
+	defer os.RemoveAll(dir)
+
+	src, err := generate(dir, []string{"User"}, "setItems", false)
+	assert.Nil(t, err, "generate")
+	out := string(src)
+	for _, wanted := range []string{
+		`"github.com/yikailee/golang/middlewares"`,
+		`func (u *User) Bind(r *http.Request, opts ...middlewares.Option) error {`,
+		`middlewares.NewBindValues(r, []string{"page", "scores"}, opts...)`,
+		`values.Lookup("X-Tenant", "header")`,
+		`return middlewares.MissingParam("id")`,
+		`u.dtoBindPage(middlewares.StringValues("1"))`,
+		`u.dtoBindScores(middlewares.StringValues("1.5", "2"))`,
+		`return middlewares.NotEnoughValues(len(s), n)`,
+		`s[i] = float32(x)`,
+		`u.Page = int(x)`,
+		`values.CheckUnknownParams("id,path", "X-Tenant,header", "page", "scores", "ids,query")`,
+		`values.LookupCollection("ids", "query", "csv")`,
+	} {
+		assert.True(t, strings.Contains(out, wanted), wanted)
+	}
+	assert.False(t, strings.Contains(out, "values.RequireBody()"), "no required body field")
+	assert.False(t, strings.Contains(out, "values.Strict()"), "no strict marker")
+}
+
+func TestGenerateRequireBodyAndStrict(t *testing.T) {
+	dir := writeDTOSource(t, "package api\n\ntype User struct {\n\t_ struct{} `strict:\"true\"`\n\tName string `dto:\"name,body\"`\n\tsetItems map[string]bool\n}\n")
+	defer os.RemoveAll(dir)
+
+	src, err := generate(dir, []string{"User"}, "setItems", false)
+	assert.Nil(t, err, "generate")
+	assert.True(t, strings.Contains(string(src), "values.RequireBody()"), "required body field")
+	assert.True(t, strings.Contains(string(src), "values.Strict()"), "strict marker")
+}
+
+func TestGenerateError(t *testing.T) {
+	testData := []struct {
+		name string
+		src  string
+	}{
+		{
+			name: "nested struct",
+			src:  "package api\n\ntype Filter struct{}\n\ntype User struct {\n\tFilter Filter `dto:\"filter\"`\n\tsetItems map[string]bool\n}\n",
+		},
+		{
+			name: "bad default",
+			src:  "package api\n\ntype User struct {\n\tAge int `dto:\"age\" default:\"ten\"`\n\tsetItems map[string]bool\n}\n",
+		},
+		{
+			name: "context source",
+			src:  "package api\n\ntype User struct {\n\tID int64 `dto:\"id,context\"`\n\tsetItems map[string]bool\n}\n",
+		},
+		{
+			name: "missing set field",
+			src:  "package api\n\ntype User struct {\n\tID int64 `dto:\"id\"`\n}\n",
+		},
+		{
+			name: "unknown collection format",
+			src:  "package api\n\ntype User struct {\n\tIDs []int `dto:\"ids\" collection:\"tsv\"`\n\tsetItems map[string]bool\n}\n",
+		},
+		{
+			name: "collection on a scalar",
+			src:  "package api\n\ntype User struct {\n\tID int `dto:\"id\" collection:\"csv\"`\n\tsetItems map[string]bool\n}\n",
+		},
+		{
+			name: "embedded struct",
+			src:  "package api\n\ntype Page struct{}\n\ntype User struct {\n\tPage\n\tsetItems map[string]bool\n}\n",
+		},
+	}
+
+	for _, test := range testData {
+		dir := writeDTOSource(t, test.src)
+		_, err := generate(dir, []string{"User"}, "setItems", false)
+		assert.NotNil(t, err, test.name)
+		os.RemoveAll(dir)
+	}
+}
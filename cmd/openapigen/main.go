@@ -0,0 +1,111 @@
+// Command openapigen writes the OpenAPI document of the routes of a router.
+//
+// It builds and runs a temporary program importing the package of the
+// router, so it has to run inside the module of that package:
+//
+//	openapigen -pkg example.com/api/routes -func NewRouter -o openapi.json
+//
+// The function takes no argument and returns the *mux.Router whose routes
+// are described with openapi.Describe.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"text/template"
+)
+
+var (
+	pkg     = flag.String("pkg", "", "import path of the package of the router function")
+	fn      = flag.String("func", "Router", "name of the function returning the *mux.Router")
+	title   = flag.String("title", "API", "title of the document")
+	version = flag.String("version", "0.0.0", "version of the API")
+	output  = flag.String("o", "openapi.json", "output file name")
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("openapigen: ")
+	flag.Parse()
+	if *pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	out, err := filepath.Abs(*output)
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := program(*pkg, *fn, *title, *version, out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// inside the working directory so that the package resolves in its module
+	dir, err := ioutil.TempDir(".", "openapigen")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), src, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", "./"+filepath.Base(dir))
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		os.RemoveAll(dir)
+		log.Fatal(err)
+	}
+}
+
+var programTemplate = template.Must(template.New("main").Parse(`// Code generated by openapigen. DO NOT EDIT.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+
+	"github.com/yikailee/golang/openapi"
+	routes {{printf "%q" .Pkg}}
+)
+
+func main() {
+	doc, err := openapi.Generate(routes.{{.Func}}(), openapi.Info{Title: {{printf "%q" .Title}}, Version: {{printf "%q" .Version}}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile({{printf "%q" .Output}}, append(b, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+`))
+
+// program returns the source of the program writing the document of the
+// router returned by pkg.fn to output.
+func program(pkg, fn, title, version, output string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := programTemplate.Execute(&buf, struct {
+		Pkg, Func, Title, Version, Output string
+	}{pkg, fn, title, version, output})
+	if err != nil {
+		return nil, err
+	}
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("bad -pkg or -func: %v", err)
+	}
+	return src, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgramHappyPath(t *testing.T) {
+	src, err := program("example.com/api/routes", "NewRouter", "users", "1.0.0", "/tmp/openapi.json")
+	assert.Nil(t, err, "program")
+	out := string(src)
+	for _, wanted := range []string{
+		`routes "example.com/api/routes"`,
+		`openapi.Generate(routes.NewRouter(), openapi.Info{Title: "users", Version: "1.0.0"})`,
+		`ioutil.WriteFile("/tmp/openapi.json", append(b, '\n'), 0644)`,
+	} {
+		assert.True(t, strings.Contains(out, wanted), wanted)
+	}
+}
+
+func TestProgramError(t *testing.T) {
+	_, err := program("example.com/api/routes", "New Router", "users", "1.0.0", "openapi.json")
+	assert.NotNil(t, err, "bad function name")
+}
@@ -2,61 +2,253 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
-	"path"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 )
 
 const (
-	configFileName = "config.json"
+	configFileBase = "config"
+	configFileName = configFileBase + ".json"
 )
 
-var ConfigParams = &struct {
-	ListenPort int `json:"listenPort"`
-}{}
+// Config holds the settings of the server. Each setting is read, from the
+// lowest to the highest precedence, from its default tag, the config file,
+// its environment variable and its command line flag.
+type Config struct {
+	ListenPort int              `json:"listenPort" default:"8080" validate:"min=1,max=65535" doc:"port of the server"`
+	Server     ServerConfig     `json:"server"`
+	Log        LogConfig        `json:"log"`
+	Cache      CacheConfig      `json:"cache"`
+	Database   DatabaseConfig   `json:"database"`
+	Middleware MiddlewareConfig `json:"middleware"`
+}
+
+// ConfigParams holds the config of the last successful Load, the defaults
+// before. It is no longer loaded at import time, call Params or Load first.
+// The reloads of Watch do not update it, use Current.
+var ConfigParams = defaultParams()
+
+func defaultParams() *Config {
+	cfg, err := defaults()
+	if err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// Options selects the config file read by Load.
+type Options struct {
+	// Path is the config file to read, the search paths are ignored when
+	// it is set.
+	Path string
+	// SearchPaths are the directories searched in order for a config file,
+	// config.json, config.yaml, config.yml, config.toml, config.jsonc or
+	// config.json5, DefaultSearchPaths() when nil.
+	SearchPaths []string
+
+	// Profile selects the overlay file deep merged over the config file,
+	// config.<Profile>.<extension> next to config.json. The
+	// <EnvPrefix>_PROFILE environment variable when empty.
+	Profile string
+	// Remote is fetched and merged over the config file and its profile
+	// overlay when set.
+	Remote *Remote
+
+	// EnvPrefix prefixes the environment variables of the settings, "APP"
+	// when empty.
+	EnvPrefix string
+	// LookupEnv reads the environment, os.LookupEnv when nil.
+	LookupEnv func(key string) (string, bool)
+	// Args are the command line arguments holding the setting flags,
+	// os.Args[1:] when nil. Other arguments are ignored.
+	Args []string
+}
+
+func (o Options) envPrefix() string {
+	if o.EnvPrefix == "" {
+		return defaultEnvPrefix
+	}
+	return o.EnvPrefix
+}
+
+func (o Options) lookupEnv() func(key string) (string, bool) {
+	if o.LookupEnv == nil {
+		return os.LookupEnv
+	}
+	return o.LookupEnv
+}
+
+// DefaultSearchPaths returns $XDG_CONFIG_HOME/<executable name> (or
+// ~/.config/<executable name>), the working directory and the directory of
+// the executable.
+func DefaultSearchPaths() []string {
+	var paths []string
+	name := filepath.Base(os.Args[0])
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		paths = append(paths, filepath.Join(xdg, name))
+	} else if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".config", name))
+	}
+	if wd, err := os.Getwd(); err == nil {
+		paths = append(paths, wd)
+	}
+	// Get excutable directory
+	if exeDir, err := filepath.Abs(filepath.Dir(os.Args[0])); err == nil {
+		paths = append(paths, exeDir)
+	}
+	return paths
+}
 
-func init() {
-	if err := loadConfig(); err != nil {
-		log.Fatal(err)
+// Load reads the config file selected by opts and stores it in
+// ConfigParams. When no search path has a config file, the defaults apply,
+// see WriteDefaultFile to create one.
+func Load(opts Options) (*Config, error) {
+	cfg, _, err := LoadSettings(opts)
+	return cfg, err
+}
+
+// LoadSettings is Load also returning the effective value and the source
+// of each setting, see PrintSettings.
+func LoadSettings(opts Options) (*Config, []Setting, error) {
+	b, path, err := readFile(opts)
+	if err != nil {
+		return nil, nil, err
 	}
+	cfg, settings, err := setConfig(b, path, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	markLoaded()
+	return cfg, settings, nil
+}
+
+var (
+	paramsOnce sync.Once
+	paramsErr  error
+)
+
+// Params loads the config from the default search paths on its first call
+// and returns ConfigParams. It does not load again after a successful Load.
+func Params() (*Config, error) {
+	paramsOnce.Do(func() {
+		paramsErr = loadConfig()
+	})
+	return ConfigParams, paramsErr
+}
+
+// markLoaded keeps Params from loading over the config of a successful Load.
+func markLoaded() {
+	paramsOnce.Do(func() {})
+	paramsErr = nil
 }
 
 var loadConfig = func() error {
-	f, err := loadFile()
+	f, path, err := loadFile()
 	if err != nil {
 		return err
 	}
 
-	err = json.Unmarshal(f, ConfigParams)
+	_, _, err = setConfig(f, path, Options{})
+	return err
+}
+
+var loadFile = func() ([]byte, string, error) {
+	return readFile(Options{})
+}
+
+func setConfig(b []byte, path string, opts Options) (*Config, []Setting, error) {
+	cfg, settings, err := build(b, path, opts)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	return nil
+	*ConfigParams = *cfg
+	current.Store(cfg)
+	return cfg, settings, nil
 }
 
-var loadFile = func() (b []byte, err error) {
-	// Get excutable directory
-	exeDir, e := filepath.Abs(filepath.Dir(os.Args[0]))
-	if e != nil {
-		log.Fatal(e)
-	}
-
-	cfgFilePath := path.Join(exeDir, configFileName)
-	if _, err = os.Stat(cfgFilePath); err != nil {
-		if os.IsNotExist(err) {
-			// create a default config file if not exist
-			ConfigParams.ListenPort = 8080 // default port 8080
-			b, err = json.Marshal(ConfigParams)
-			if err != nil {
-				return
+// readFile returns the content of the config file converted to JSON and its
+// path, an empty object and no path when there is no config file.
+func readFile(opts Options) ([]byte, string, error) {
+	if opts.Path != "" {
+		b, err := readConfigFile(opts.Path)
+		return b, opts.Path, err
+	}
+	paths := opts.SearchPaths
+	if paths == nil {
+		paths = DefaultSearchPaths()
+	}
+	if len(paths) == 0 {
+		return nil, "", fmt.Errorf("config: no search path for %s", configFileName)
+	}
+	for _, dir := range paths {
+		for _, ext := range formatExtensions {
+			path := filepath.Join(dir, configFileBase+ext)
+			b, err := readConfigFile(path)
+			if err == nil {
+				return b, path, nil
+			}
+			if !os.IsNotExist(err) {
+				return nil, "", err
 			}
-			err = ioutil.WriteFile(cfgFilePath, b, 0644)
-			return b, err
 		}
 	}
 
-	b, err = ioutil.ReadFile(cfgFilePath)
-	return
+	// no config file, the defaults apply
+	return []byte("{}"), "", nil
+}
+
+// WriteDefaultFile writes the default config to path, in the format of its
+// extension. The yaml, toml, jsonc and json5 files document each setting in
+// a comment. It fails when path exists unless overwrite is set, in which
+// case the file is replaced only once the new content is written.
+func WriteDefaultFile(path string, overwrite bool) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	f, ok := formats[ext]
+	if !ok {
+		return fmt.Errorf("config: unknown format %q", ext)
+	}
+	cfg, err := defaults()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	content, err := f.fromJSON(b)
+	if err != nil {
+		return err
+	}
+	if f.comment != nil {
+		content = f.comment(content, configDocs(reflect.TypeOf(Config{}), ""))
+	}
+	if overwrite {
+		return writeFileAtomic(path, content, 0644)
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// readConfigFile reads the config file path and converts it to JSON.
+func readConfigFile(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = formatOf(path).toJSON(b); err != nil {
+		return nil, fmt.Errorf("config: %s: %v", path, err)
+	}
+	return b, nil
 }
@@ -2,14 +2,17 @@ package config
 
 import (
 	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func mockLoadFile(data []byte, err error) func() ([]byte, error) {
-	return func() ([]byte, error) {
-		return data, err
+func mockLoadFile(data []byte, err error) func() ([]byte, string, error) {
+	return func() ([]byte, string, error) {
+		return data, "", err
 	}
 }
 
@@ -83,3 +86,156 @@ func TestConfigError(t *testing.T) {
 		assert.NotNil(t, err, test.name)
 	}
 }
+
+func writeConfigFile(t *testing.T, dir, content string) {
+	if err := ioutil.WriteFile(filepath.Join(dir, configFileName), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadHappyPath(t *testing.T) {
+	dir1, dir2, dir3 := t.TempDir(), t.TempDir(), t.TempDir()
+	writeConfigFile(t, dir2, `{"listenPort": 9090}`)
+	writeConfigFile(t, dir3, `{"listenPort": 7070}`)
+	explicit := filepath.Join(t.TempDir(), "app.json")
+	if err := ioutil.WriteFile(explicit, []byte(`{"listenPort": 6060}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testData := []struct {
+		name   string
+		opts   Options
+		wanted int
+	}{
+		{
+			name:   "first search path with a file",
+			opts:   Options{SearchPaths: []string{dir1, dir2, dir3}},
+			wanted: 9090,
+		},
+		{
+			name:   "explicit path",
+			opts:   Options{Path: explicit, SearchPaths: []string{dir2}},
+			wanted: 6060,
+		},
+	}
+
+	for _, test := range testData {
+		cfg, err := Load(test.opts)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wanted, cfg.ListenPort, test.name)
+		assert.Equal(t, test.wanted, ConfigParams.ListenPort, test.name)
+	}
+}
+
+func TestLoadWithoutFile(t *testing.T) {
+	dir1, dir2 := t.TempDir(), t.TempDir()
+	cfg, settings, err := LoadSettings(Options{SearchPaths: []string{dir1, dir2}})
+	assert.Nil(t, err, "load")
+	assert.Equal(t, 8080, cfg.ListenPort, "default port")
+	assert.Equal(t, SourceDefault, settings[0].Source, "default port")
+
+	for _, dir := range []string{dir1, dir2} {
+		files, err := ioutil.ReadDir(dir)
+		assert.Nil(t, err, "no file written")
+		assert.Empty(t, files, "no file written")
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{"listenPort": "8080"}`)
+	ConfigParams.ListenPort = 1234
+
+	testData := []struct {
+		name string
+		opts Options
+	}{
+		{
+			name: "missing explicit path",
+			opts: Options{Path: filepath.Join(dir, "missing.json")},
+		},
+		{
+			name: "incorrect value format",
+			opts: Options{SearchPaths: []string{dir}},
+		},
+		{
+			name: "no search path",
+			opts: Options{SearchPaths: []string{}},
+		},
+	}
+
+	for _, test := range testData {
+		_, err := Load(test.opts)
+		assert.NotNil(t, err, test.name)
+		assert.Equal(t, 1234, ConfigParams.ListenPort, test.name)
+	}
+}
+
+func TestParams(t *testing.T) {
+	orgFunc := loadFile
+	defer func() {
+		loadFile = orgFunc
+	}()
+	paramsOnce, paramsErr = sync.Once{}, nil
+	calls := 0
+	loadFile = func() ([]byte, string, error) {
+		calls++
+		return []byte(`{"listenPort": 5050}`), "", nil
+	}
+
+	for i := 0; i < 2; i++ {
+		cfg, err := Params()
+		assert.Nil(t, err, "params")
+		assert.Equal(t, 5050, cfg.ListenPort, "params")
+	}
+	assert.Equal(t, 1, calls, "loaded once")
+}
+
+func TestParamsAfterLoad(t *testing.T) {
+	orgFunc := loadFile
+	defer func() {
+		loadFile = orgFunc
+	}()
+	calls := 0
+	loadFile = func() ([]byte, string, error) {
+		calls++
+		return []byte(`{"listenPort": 5050}`), "", nil
+	}
+
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{"listenPort": 6060}`)
+	_, err := Load(Options{Path: filepath.Join(dir, configFileName), LookupEnv: mockEnv(nil), Args: []string{}})
+	assert.Nil(t, err, "load")
+	cfg, err := Params()
+	assert.Nil(t, err, "params")
+	assert.Equal(t, 6060, cfg.ListenPort, "params")
+	assert.Equal(t, 0, calls, "not loaded again")
+}
+
+func TestParamsDefaults(t *testing.T) {
+	cfg, err := defaults()
+	assert.Nil(t, err, "defaults")
+	assert.Equal(t, cfg, defaultParams(), "defaults before Params")
+	assert.Equal(t, 8080, defaultParams().ListenPort, "defaults before Params")
+}
+
+func TestParamsOverlay(t *testing.T) {
+	orgFunc := loadFile
+	defer func() {
+		loadFile = orgFunc
+	}()
+	paramsOnce, paramsErr = sync.Once{}, nil
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{"listenPort": 5050}`)
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.test.json"), []byte(`{"listenPort": 6060}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(defaultEnvPrefix+"_PROFILE", "test")
+	loadFile = func() ([]byte, string, error) {
+		return readFile(Options{Path: filepath.Join(dir, configFileName)})
+	}
+
+	cfg, err := Params()
+	assert.Nil(t, err, "params")
+	assert.Equal(t, 6060, cfg.ListenPort, "overlay next to the config file")
+}
@@ -0,0 +1,42 @@
+package config
+
+import "reflect"
+
+// Difference is a setting of the config file whose value is not the default
+// one.
+type Difference struct {
+	Key     string
+	Default interface{}
+	Value   interface{}
+}
+
+// DiffDefaults returns the settings of the config file selected by opts
+// merged with its profile overlay and the remote config whose value is not
+// the default one. The environment and the flags are ignored, except for
+// selecting the profile.
+func DiffDefaults(opts Options) ([]Difference, error) {
+	b, path, err := readFile(opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.Profile = profileOf(opts)
+	opts.LookupEnv = func(string) (string, bool) { return "", false }
+	opts.Args = []string{}
+	_, settings, err := build(b, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	opts.Profile, opts.Remote = "", nil
+	_, defaults, err := build([]byte("{}"), "", opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var diffs []Difference
+	for i, s := range settings {
+		if !reflect.DeepEqual(s.Value, defaults[i].Value) {
+			diffs = append(diffs, Difference{Key: s.Key, Default: defaults[i].Value, Value: s.Value})
+		}
+	}
+	return diffs, nil
+}
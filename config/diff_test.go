@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffDefaults(t *testing.T) {
+	testData := []struct {
+		name    string
+		content string
+		wanted  []Difference
+	}{
+		{
+			name:    "empty file",
+			content: `{}`,
+		},
+		{
+			name:    "default values",
+			content: `{"listenPort": 8080, "log": {"level": "info"}}`,
+		},
+		{
+			name:    "changed values",
+			content: `{"listenPort": 9090, "server": {"readTimeout": "1m"}, "cache": {"ttls": {"users": "1h"}}}`,
+			wanted: []Difference{
+				{Key: "listenPort", Default: 8080, Value: 9090},
+				{Key: "server.readTimeout", Default: Duration(10 * time.Second), Value: Duration(time.Minute)},
+				{Key: "cache.ttls", Default: map[string]Duration{}, Value: map[string]Duration{"users": Duration(time.Hour)}},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		dir := t.TempDir()
+		writeConfigFile(t, dir, test.content)
+		diffs, err := DiffDefaults(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(map[string]string{"APP_LOG_LEVEL": "debug"})})
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wanted, diffs, test.name)
+	}
+}
@@ -0,0 +1,354 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// format converts a config file format from and to JSON, which the
+// settings are decoded from. comment adds the docs of the settings, by json
+// path, to a file written by fromJSON, it is nil for formats without
+// comments.
+type format struct {
+	toJSON   func(b []byte) ([]byte, error)
+	fromJSON func(b []byte) ([]byte, error)
+	comment  func(b []byte, docs map[string]string) []byte
+}
+
+// formatExtensions lists the extensions of the config files, in the order
+// the search paths are searched for config.<extension>.
+var formatExtensions = []string{".json", ".yaml", ".yml", ".toml", ".jsonc", ".json5"}
+
+// formats maps the config file extensions to their format.
+var formats = map[string]format{
+	".json":  {toJSON: identity, fromJSON: indentJSON},
+	".yaml":  {toJSON: yamlToJSON, fromJSON: jsonToYAML, comment: commentYAML},
+	".yml":   {toJSON: yamlToJSON, fromJSON: jsonToYAML, comment: commentYAML},
+	".toml":  {toJSON: tomlToJSON, fromJSON: jsonToTOML, comment: commentTOML},
+	".jsonc": {toJSON: jsoncToJSON, fromJSON: indentJSON, comment: commentJSON},
+	".json5": {toJSON: jsoncToJSON, fromJSON: indentJSON, comment: commentJSON},
+}
+
+// formatOf returns the format of the file path, JSON for unknown
+// extensions.
+func formatOf(path string) format {
+	if f, ok := formats[strings.ToLower(filepath.Ext(path))]; ok {
+		return f
+	}
+	return formats[".json"]
+}
+
+func identity(b []byte) ([]byte, error) {
+	return b, nil
+}
+
+func indentJSON(b []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func yamlToJSON(b []byte) ([]byte, error) {
+	var v map[string]interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return []byte("{}"), nil
+	}
+	n, err := jsonValue(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(n)
+}
+
+// jsonValue converts the maps with non string keys decoded from YAML.
+func jsonValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			n, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = n
+		}
+		return v, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			n, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = n
+		}
+		return m, nil
+	case []interface{}:
+		for i, e := range v {
+			n, err := jsonValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+		return v, nil
+	}
+	return v, nil
+}
+
+func jsonToYAML(b []byte) ([]byte, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(typedNumbers(v))
+}
+
+func tomlToJSON(b []byte) ([]byte, error) {
+	var v map[string]interface{}
+	if err := toml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(v)
+}
+
+func jsonToTOML(b []byte) ([]byte, error) {
+	var v map[string]interface{}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return toml.Marshal(typedNumbers(v))
+}
+
+// typedNumbers turns the json.Number values into the integers and floats
+// YAML and TOML tell apart.
+func typedNumbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = typedNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = typedNumbers(e)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
+
+// jsoncToJSON converts JSON with comments to JSON. It also accepts the
+// trailing commas, unquoted keys and single quoted strings of JSON5.
+func jsoncToJSON(b []byte) ([]byte, error) {
+	var out bytes.Buffer
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '"' || c == '\'':
+			end, err := writeString(&out, b, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+		case c == '/' && i+1 < len(b) && b[i+1] == '/':
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(b) && b[i+1] == '*':
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += end + 3
+			out.WriteByte(' ')
+		case c == ',':
+			if next := nextToken(b, i+1); next == '}' || next == ']' {
+				continue
+			}
+			out.WriteByte(c)
+		case isIdentStart(c):
+			j := i
+			for j < len(b) && isIdentPart(b[j]) {
+				j++
+			}
+			if nextToken(b, j) == ':' {
+				out.WriteString(`"` + string(b[i:j]) + `"`)
+			} else {
+				out.Write(b[i:j])
+			}
+			i = j - 1
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// writeString writes the string starting at b[start] as a double quoted
+// JSON string and returns the index of its closing quote.
+func writeString(out *bytes.Buffer, b []byte, start int) (int, error) {
+	quote := b[start]
+	out.WriteByte('"')
+	for i := start + 1; i < len(b); i++ {
+		c := b[i]
+		switch {
+		case c == '\\' && i+1 < len(b):
+			if quote == '\'' && b[i+1] == '\'' {
+				out.WriteByte('\'')
+			} else {
+				out.Write(b[i : i+2])
+			}
+			i++
+		case c == quote:
+			out.WriteByte('"')
+			return i, nil
+		case c == '"':
+			out.WriteString(`\"`)
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return 0, fmt.Errorf("unterminated string")
+}
+
+// nextToken returns the next byte of b from i which is not a space or in a
+// comment, 0 at the end.
+func nextToken(b []byte, i int) byte {
+	for i < len(b) {
+		switch {
+		case b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r':
+			i++
+		case bytes.HasPrefix(b[i:], []byte("//")):
+			for i < len(b) && b[i] != '\n' {
+				i++
+			}
+		case bytes.HasPrefix(b[i:], []byte("/*")):
+			end := bytes.Index(b[i+2:], []byte("*/"))
+			if end < 0 {
+				return 0
+			}
+			i += end + 4
+		default:
+			return b[i]
+		}
+	}
+	return 0
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || c >= '0' && c <= '9'
+}
+
+// commentJSON adds the docs to JSON written by indentJSON, one key per line.
+func commentJSON(b []byte, docs map[string]string) []byte {
+	return commentIndented(b, "//", docs, func(line string) (string, bool, bool) {
+		if !strings.HasPrefix(line, `"`) {
+			return "", false, false
+		}
+		i := strings.Index(line, `":`)
+		if i < 0 {
+			return "", false, false
+		}
+		return line[1:i], strings.HasSuffix(line, "{"), true
+	})
+}
+
+// commentYAML adds the docs to YAML written by jsonToYAML.
+func commentYAML(b []byte, docs map[string]string) []byte {
+	return commentIndented(b, "#", docs, func(line string) (string, bool, bool) {
+		i := strings.Index(line, ":")
+		if i < 0 || strings.HasPrefix(line, "-") {
+			return "", false, false
+		}
+		return strings.Trim(line[:i], `"'`), i == len(line)-1, true
+	})
+}
+
+// commentIndented adds the docs before the keys of a file nesting the
+// objects by indentation. key parses a trimmed line into its key and
+// whether it opens an object.
+func commentIndented(b []byte, comment string, docs map[string]string, key func(line string) (name string, opens, ok bool)) []byte {
+	type level struct {
+		indent int
+		name   string
+	}
+	var out bytes.Buffer
+	var stack []level
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		name, opens, ok := key(trimmed)
+		if ok {
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				stack = stack[:len(stack)-1]
+			}
+			path := name
+			if len(stack) > 0 {
+				path = stack[len(stack)-1].name + "." + name
+			}
+			if doc, ok := docs[path]; ok {
+				fmt.Fprintf(&out, "%s%s %s\n", line[:indent], comment, doc)
+			}
+			if opens {
+				stack = append(stack, level{indent: indent, name: path})
+			}
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes()
+}
+
+// commentTOML adds the docs to TOML written by jsonToTOML, before the table
+// headers and the keys.
+func commentTOML(b []byte, docs map[string]string) []byte {
+	var out bytes.Buffer
+	table := ""
+	for _, line := range strings.SplitAfter(string(b), "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		path := ""
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			table = strings.Trim(trimmed, "[]")
+			path = table
+		case strings.Contains(trimmed, "="):
+			path = strings.Trim(strings.TrimSpace(trimmed[:strings.Index(trimmed, "=")]), `"'`)
+			if table != "" {
+				path = table + "." + path
+			}
+		}
+		if doc, ok := docs[path]; ok {
+			fmt.Fprintf(&out, "%s# %s\n", indent, doc)
+		}
+		out.WriteString(line)
+	}
+	return out.Bytes()
+}
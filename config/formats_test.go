@@ -0,0 +1,175 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatsHappyPath(t *testing.T) {
+	testData := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "json",
+			file:    "config.json",
+			content: `{"listenPort": 9090}`,
+		},
+		{
+			name:    "yaml",
+			file:    "config.yaml",
+			content: "# port of the server\nlistenPort: 9090\n",
+		},
+		{
+			name:    "yml",
+			file:    "config.yml",
+			content: "listenPort: 9090 # comment\n",
+		},
+		{
+			name:    "toml",
+			file:    "config.toml",
+			content: "# port of the server\nlistenPort = 9090\n",
+		},
+		{
+			name:    "jsonc",
+			file:    "config.jsonc",
+			content: "{\n  // port of the server\n  \"listenPort\": 9090, /* trailing comma */\n}\n",
+		},
+		{
+			name:    "json5",
+			file:    "config.json5",
+			content: "{'listenPort': 9090, // it's a 'comment'\n}",
+		},
+	}
+
+	for _, test := range testData {
+		dir := t.TempDir()
+		if err := ioutil.WriteFile(filepath.Join(dir, test.file), []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, settings, err := LoadSettings(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(nil), Args: []string{}})
+		assert.Nil(t, err, test.name)
+		if err != nil {
+			continue
+		}
+		assert.Equal(t, 9090, cfg.ListenPort, test.name)
+		assert.Equal(t, filepath.Join(dir, test.file), settings[0].Origin, test.name)
+	}
+}
+
+func TestFormatsError(t *testing.T) {
+	testData := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name:    "yaml type",
+			file:    "config.yaml",
+			content: "listenPort: http\n",
+		},
+		{
+			name:    "malformed yaml",
+			file:    "config.yaml",
+			content: "listenPort: [1\n",
+		},
+		{
+			name:    "malformed toml",
+			file:    "config.toml",
+			content: "listenPort = \n",
+		},
+		{
+			name:    "unterminated comment",
+			file:    "config.jsonc",
+			content: "{\"listenPort\": 1 /* \n}",
+		},
+		{
+			name:    "comments in json",
+			file:    "config.json",
+			content: "{// comment\n\"listenPort\": 1}",
+		},
+	}
+
+	for _, test := range testData {
+		path := filepath.Join(t.TempDir(), test.file)
+		if err := ioutil.WriteFile(path, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(Options{Path: path, LookupEnv: mockEnv(nil), Args: []string{}})
+		assert.NotNil(t, err, test.name)
+	}
+}
+
+func TestDefaultFileFormat(t *testing.T) {
+	testData := []struct {
+		name   string
+		file   string
+		wanted []string
+	}{
+		{
+			name:   "json",
+			file:   "config.json",
+			wanted: []string{"{\n  \"listenPort\": 8080,\n", "    \"maxHeaderBytes\": 1048576,\n"},
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			wanted: []string{
+				"# port of the server\nlistenPort: 8080\n",
+				"    # max size of the request headers in bytes\n    maxHeaderBytes: 1048576\n",
+				"    # HTTPS is served when both files are set\n    tls:\n        # PEM certificate chain\n        certFile: \"\"\n",
+			},
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			wanted: []string{
+				"# port of the server\nlistenPort = 8080\n",
+				"# max size of the request headers in bytes\nmaxHeaderBytes = 1048576\n",
+				"# HTTPS is served when both files are set\n[server.tls]\n# PEM certificate chain\ncertFile = ''\n",
+			},
+		},
+		{
+			name: "jsonc",
+			file: "config.jsonc",
+			wanted: []string{
+				"{\n  // port of the server\n  \"listenPort\": 8080,\n",
+				"    // HTTPS is served when both files are set\n    \"tls\": {\n      // PEM certificate chain\n      \"certFile\": \"\",\n",
+			},
+		},
+	}
+
+	defaultConfig, err := defaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range testData {
+		dir := t.TempDir()
+		err := WriteDefaultFile(filepath.Join(dir, test.file), false)
+		assert.Nil(t, err, test.name)
+		b, err := ioutil.ReadFile(filepath.Join(dir, test.file))
+		assert.Nil(t, err, test.name)
+		for _, wanted := range test.wanted {
+			assert.Contains(t, string(b), wanted, test.name)
+		}
+
+		// the written file loads back
+		cfg, err := Load(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(nil), Args: []string{}})
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, defaultConfig, cfg, test.name)
+	}
+
+	dir := t.TempDir()
+	assert.NotNil(t, WriteDefaultFile(filepath.Join(dir, "config.ini"), false), "unknown format")
+	writeConfigFile(t, dir, `{"listenPort": 9090}`)
+	assert.True(t, os.IsExist(WriteDefaultFile(filepath.Join(dir, configFileName), false)), "existing file")
+	assert.Nil(t, WriteDefaultFile(filepath.Join(dir, configFileName), true), "overwrite")
+	cfg, err := Load(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(nil), Args: []string{}})
+	assert.Nil(t, err, "overwrite")
+	assert.Equal(t, defaultConfig, cfg, "overwrite")
+}
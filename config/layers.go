@@ -0,0 +1,432 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+)
+
+const defaultEnvPrefix = "APP"
+
+// Sources of the settings, from the lowest to the highest precedence.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Setting is the effective value of a config setting and where it comes
+// from.
+type Setting struct {
+	Key    string // json path, "listenPort"
+	Value  interface{}
+	Source string
+	// Origin is the file, the environment variable or the flag which set
+	// the value
+	Origin string
+}
+
+// field is a setting of the Config struct. The environment variable and the
+// flag are derived from the json path (listenPort gives APP_LISTEN_PORT and
+// --listen-port), the env tag (full variable name) and the flag tag override
+// them and "-" disables them. The default tag holds the default value and
+// the validate tag the rules checked by checkRules.
+type field struct {
+	key      string
+	env      string
+	flag     string
+	def      string
+	hasDef   bool
+	validate string
+	index    []int
+	typ      reflect.Type
+}
+
+func configFields(t reflect.Type, index []int, keyPrefix, envPrefix, flagPrefix string) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fi := field{
+			key:   keyPrefix + name,
+			env:   envPrefix + envName(name),
+			flag:  flagPrefix + flagName(name),
+			index: append(append([]int(nil), index...), i),
+			typ:   f.Type,
+		}
+		if isSection(f.Type) {
+			fields = append(fields, configFields(f.Type, fi.index, fi.key+".", fi.env+"_", fi.flag+".")...)
+			continue
+		}
+		if env, ok := f.Tag.Lookup("env"); ok {
+			fi.env = env
+		}
+		if flag, ok := f.Tag.Lookup("flag"); ok {
+			fi.flag = flag
+		}
+		fi.def, fi.hasDef = f.Tag.Lookup("default")
+		fi.validate = f.Tag.Get("validate")
+		fields = append(fields, fi)
+	}
+	return fields
+}
+
+// isSection reports whether settings of type t are grouped in a nested
+// section rather than set from a single value.
+func isSection(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	return !reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	durationType        = reflect.TypeOf(time.Duration(0))
+	configDurationType  = reflect.TypeOf(Duration(0))
+)
+
+// envName turns a json name into an environment variable name:
+// listenPort gives LISTEN_PORT.
+func envName(name string) string {
+	return strings.ToUpper(splitWords(name, "_"))
+}
+
+// flagName turns a json name into a flag name: listenPort gives
+// listen-port.
+func flagName(name string) string {
+	return strings.ToLower(splitWords(name, "-"))
+}
+
+func splitWords(name, sep string) string {
+	var b strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[i-1]) ||
+			i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			b.WriteString(sep)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// setString sets the field v from the string s of a default, an environment
+// variable or a flag.
+func setString(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setString(sl.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+	case reflect.Map:
+		// name=value pairs, "users=1m,items=30s"
+		m := reflect.MakeMap(v.Type())
+		for _, p := range strings.Split(s, ",") {
+			if strings.TrimSpace(p) == "" {
+				continue
+			}
+			i := strings.IndexByte(p, '=')
+			if i < 0 {
+				return fmt.Errorf("missing = in %q", p)
+			}
+			key := reflect.New(v.Type().Key()).Elem()
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setString(key, strings.TrimSpace(p[:i])); err != nil {
+				return err
+			}
+			if err := setString(elem, strings.TrimSpace(p[i+1:])); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, elem)
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// defaults returns the config holding the default tag values.
+func defaults() (*Config, error) {
+	cfg := &Config{}
+	v := reflect.ValueOf(cfg).Elem()
+	for _, f := range configFields(v.Type(), nil, "", "", "") {
+		if !f.hasDef {
+			continue
+		}
+		if err := setString(v.FieldByIndex(f.index), f.def); err != nil {
+			return nil, fmt.Errorf("config: default of %s: %v", f.key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// build layers the defaults, the file b, its profile overlay and the remote
+// config, the environment and the flags of opts into a new Config. The
+// "${scheme:ref}" references of the files are expanded by their Resolver.
+// All the problems of the files, the environment, the flags and the validate
+// rules are reported at once in ValidationErrors.
+func build(b []byte, path string, opts Options) (*Config, []Setting, error) {
+	cfg, err := defaults()
+	if err != nil {
+		return nil, nil, err
+	}
+	raw, err := decodeObject(b)
+	if err != nil {
+		return nil, nil, fmt.Errorf("config: %v", err)
+	}
+	// the files merged over the config file, by increasing precedence
+	var layers []fileLayer
+	overlay, overlayPath, err := readOverlay(path, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	if overlay != nil {
+		layers = append(layers, fileLayer{obj: overlay, origin: overlayPath})
+	}
+	commitRemote := func() {}
+	if opts.Remote != nil {
+		rb, commit, err := opts.Remote.load(opts.Remote.cacheFile(path, opts))
+		if err != nil {
+			return nil, nil, err
+		}
+		remote, err := decodeObject(rb)
+		if err != nil {
+			return nil, nil, fmt.Errorf("config: %s: %v", opts.Remote.URL, err)
+		}
+		layers = append(layers, fileLayer{obj: remote, origin: opts.Remote.URL})
+		commitRemote = commit
+	}
+	for _, l := range layers {
+		raw = mergeOverlay(raw, l.obj)
+	}
+
+	prefix := opts.envPrefix()
+	lookupEnv := opts.lookupEnv()
+	args := opts.Args
+	if args == nil && len(os.Args) > 0 {
+		args = os.Args[1:]
+	}
+
+	v := reflect.ValueOf(cfg).Elem()
+	fields := configFields(v.Type(), nil, "", prefix+"_", "")
+	flags, err := parseFlags(fields, args)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	errs := ValidationErrors(unknownKeys(raw, v.Type(), ""))
+	_, refErrs := expandRefs(raw, "")
+	errs = append(errs, refErrs...)
+	settings := make([]Setting, 0, len(fields))
+	for _, f := range fields {
+		fv := v.FieldByIndex(f.index)
+		s := Setting{Key: f.key, Source: SourceDefault}
+		if jv, ok := lookupKey(raw, f.key); ok {
+			if err := decodeValue(fv, jv); err != nil {
+				errs = append(errs, FieldError{Path: f.key, Message: err.Error()})
+			}
+			s.Source, s.Origin = SourceFile, path
+			for i := len(layers) - 1; i >= 0; i-- {
+				if _, ok := lookupKey(layers[i].obj, f.key); ok {
+					s.Origin = layers[i].origin
+					break
+				}
+			}
+		}
+		if env, ok := lookupEnv(f.env); ok && f.env != "-" {
+			if err := setString(fv, env); err != nil {
+				errs = append(errs, FieldError{Path: f.key, Message: fmt.Sprintf("%s: %v", f.env, err)})
+			}
+			s.Source, s.Origin = SourceEnv, f.env
+		}
+		if value, ok := flags[f.flag]; ok {
+			if err := setString(fv, value); err != nil {
+				errs = append(errs, FieldError{Path: f.key, Message: fmt.Sprintf("--%s: %v", f.flag, err)})
+			}
+			s.Source, s.Origin = SourceFlag, "--"+f.flag
+		}
+		errs = append(errs, checkRules(f, fv)...)
+		s.Value = fv.Interface()
+		settings = append(settings, s)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errs
+	}
+	commitRemote()
+	return cfg, settings, nil
+}
+
+// fileLayer is a decoded file merged over the config file, origin is its
+// path or URL.
+type fileLayer struct {
+	obj    map[string]interface{}
+	origin string
+}
+
+// decodeObject decodes the JSON object b keeping its numbers as
+// json.Number.
+func decodeObject(b []byte) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// decodeValue sets v from the value jv of the decoded file.
+func decodeValue(v reflect.Value, jv interface{}) error {
+	b, err := json.Marshal(jv)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, v.Addr().Interface())
+	if e, ok := err.(*json.UnmarshalTypeError); ok {
+		return fmt.Errorf("expected %s, got %s", e.Type, e.Value)
+	}
+	return err
+}
+
+// lookupKey returns the value of the dotted key in the decoded file, whose
+// keys match case insensitively like encoding/json does.
+func lookupKey(obj map[string]interface{}, key string) (interface{}, bool) {
+	name, rest := key, ""
+	if i := strings.IndexByte(key, '.'); i >= 0 {
+		name, rest = key[:i], key[i+1:]
+	}
+	for k, v := range obj {
+		if !strings.EqualFold(k, name) {
+			continue
+		}
+		if rest == "" {
+			return v, true
+		}
+		sub, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		return lookupKey(sub, rest)
+	}
+	return nil, false
+}
+
+// parseFlags picks the config flags out of args, --name=value, --name value
+// or a lone --name for booleans. The other arguments are left to the
+// application.
+func parseFlags(fields []field, args []string) (map[string]string, error) {
+	byFlag := make(map[string]field, len(fields))
+	for _, f := range fields {
+		if f.flag != "-" {
+			byFlag[f.flag] = f
+		}
+	}
+	values := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		value, hasValue := "", false
+		if j := strings.IndexByte(name, '='); j >= 0 {
+			name, value, hasValue = name[:j], name[j+1:], true
+		}
+		f, ok := byFlag[name]
+		if !ok {
+			continue
+		}
+		if !hasValue {
+			switch {
+			case f.typ.Kind() == reflect.Bool:
+				value = "true"
+			case i+1 < len(args):
+				i++
+				value = args[i]
+			default:
+				return nil, fmt.Errorf("config: flag needs an argument: --%s", name)
+			}
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// PrintSettings writes the effective value and the source of each setting.
+func PrintSettings(w io.Writer, settings []Setting) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range settings {
+		source := s.Source
+		if s.Origin != "" {
+			source += " " + s.Origin
+		}
+		fmt.Fprintf(tw, "%s\t%v\t%s\n", s.Key, s.Value, source)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func mockEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+}
+
+func TestLayersHappyPath(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{"listenPort": 9090}`)
+	empty := t.TempDir()
+	writeConfigFile(t, empty, `{}`)
+
+	testData := []struct {
+		name   string
+		dir    string
+		env    map[string]string
+		args   []string
+		wanted Setting
+	}{
+		{
+			name:   "default",
+			dir:    empty,
+			wanted: Setting{Key: "listenPort", Value: 8080, Source: SourceDefault},
+		},
+		{
+			name:   "file",
+			dir:    dir,
+			wanted: Setting{Key: "listenPort", Value: 9090, Source: SourceFile, Origin: filepath.Join(dir, configFileName)},
+		},
+		{
+			name:   "env over file",
+			dir:    dir,
+			env:    map[string]string{"APP_LISTEN_PORT": "7070"},
+			wanted: Setting{Key: "listenPort", Value: 7070, Source: SourceEnv, Origin: "APP_LISTEN_PORT"},
+		},
+		{
+			name:   "flag over env",
+			dir:    dir,
+			env:    map[string]string{"APP_LISTEN_PORT": "7070"},
+			args:   []string{"-v", "--listen-port", "6060", "serve"},
+			wanted: Setting{Key: "listenPort", Value: 6060, Source: SourceFlag, Origin: "--listen-port"},
+		},
+		{
+			name:   "last flag wins",
+			dir:    dir,
+			args:   []string{"--listen-port=1", "-listen-port=2"},
+			wanted: Setting{Key: "listenPort", Value: 2, Source: SourceFlag, Origin: "--listen-port"},
+		},
+		{
+			name:   "flags after -- are ignored",
+			dir:    dir,
+			args:   []string{"--", "--listen-port=1"},
+			wanted: Setting{Key: "listenPort", Value: 9090, Source: SourceFile, Origin: filepath.Join(dir, configFileName)},
+		},
+	}
+
+	for _, test := range testData {
+		args := test.args
+		if args == nil {
+			args = []string{}
+		}
+		cfg, settings, err := LoadSettings(Options{SearchPaths: []string{test.dir}, LookupEnv: mockEnv(test.env), Args: args})
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wanted, settings[0], test.name)
+		assert.Equal(t, test.wanted.Value, cfg.ListenPort, test.name)
+	}
+}
+
+func TestLayersError(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{}`)
+
+	testData := []struct {
+		name string
+		env  map[string]string
+		args []string
+	}{
+		{
+			name: "bad env value",
+			env:  map[string]string{"APP_LISTEN_PORT": "http"},
+			args: []string{},
+		},
+		{
+			name: "bad flag value",
+			args: []string{"--listen-port=http"},
+		},
+		{
+			name: "missing flag value",
+			args: []string{"--listen-port"},
+		},
+	}
+
+	for _, test := range testData {
+		_, err := Load(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(test.env), Args: test.args})
+		assert.NotNil(t, err, test.name)
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{}`)
+	cfg, err := Load(Options{
+		SearchPaths: []string{dir},
+		EnvPrefix:   "USERS",
+		LookupEnv:   mockEnv(map[string]string{"USERS_LISTEN_PORT": "3000", "APP_LISTEN_PORT": "4000"}),
+		Args:        []string{},
+	})
+	assert.Nil(t, err, "load")
+	assert.Equal(t, 3000, cfg.ListenPort, "prefixed env")
+}
+
+func TestDerivedNames(t *testing.T) {
+	testData := []struct {
+		name string
+		env  string
+		flag string
+	}{
+		{name: "listenPort", env: "LISTEN_PORT", flag: "listen-port"},
+		{name: "maxHeaderBytes", env: "MAX_HEADER_BYTES", flag: "max-header-bytes"},
+		{name: "TLSCert", env: "TLS_CERT", flag: "tls-cert"},
+		{name: "level", env: "LEVEL", flag: "level"},
+	}
+
+	for _, test := range testData {
+		assert.Equal(t, test.env, envName(test.name), test.name)
+		assert.Equal(t, test.flag, flagName(test.name), test.name)
+	}
+}
+
+func TestPrintSettings(t *testing.T) {
+	var buf bytes.Buffer
+	err := PrintSettings(&buf, []Setting{
+		{Key: "listenPort", Value: 7070, Source: SourceEnv, Origin: "APP_LISTEN_PORT"},
+		{Key: "logLevel", Value: "info", Source: SourceDefault},
+	})
+	assert.Nil(t, err, "print")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, []string{
+		"listenPort  7070  env APP_LISTEN_PORT",
+		"logLevel    info  default",
+	}, lines, "print")
+}
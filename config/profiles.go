@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// profileOf returns the profile selected by opts, "" for none.
+func profileOf(opts Options) string {
+	if opts.Profile != "" {
+		return opts.Profile
+	}
+	profile, _ := opts.lookupEnv()(opts.envPrefix() + "_PROFILE")
+	return profile
+}
+
+// overlayFile returns the path of the overlay file of the profile selected
+// by opts, <name>.<profile>.<extension> next to the config file path
+// (config.prod.yaml for config.json), or config.<profile>.<extension> in the
+// search paths when there is no config file. It returns "" when no profile
+// is selected.
+func overlayFile(path string, opts Options) (string, error) {
+	profile := profileOf(opts)
+	if profile == "" {
+		return "", nil
+	}
+	if strings.ContainsAny(profile, `/\`) || profile == "." || profile == ".." {
+		return "", fmt.Errorf("config: illegal profile %q", profile)
+	}
+	dirs, name := []string{filepath.Dir(path)}, strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if path == "" {
+		dirs, name = opts.SearchPaths, configFileBase
+		if dirs == nil {
+			dirs = DefaultSearchPaths()
+		}
+	}
+	for _, dir := range dirs {
+		for _, ext := range formatExtensions {
+			overlay := filepath.Join(dir, name+"."+profile+ext)
+			if _, err := os.Stat(overlay); err == nil {
+				return overlay, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("config: no config file for profile %q", profile)
+}
+
+// readOverlay reads the overlay file of the profile selected by opts, see
+// overlayFile.
+func readOverlay(path string, opts Options) (map[string]interface{}, string, error) {
+	overlay, err := overlayFile(path, opts)
+	if err != nil || overlay == "" {
+		return nil, "", err
+	}
+	b, err := readConfigFile(overlay)
+	if err != nil {
+		return nil, "", err
+	}
+	obj, err := decodeObject(b)
+	if err != nil {
+		return nil, "", fmt.Errorf("config: %s: %v", overlay, err)
+	}
+	return obj, overlay, nil
+}
+
+// mergeOverlay deep merges the objects of overlay into base, whose keys
+// match case insensitively. A null value of overlay unsets the key of base.
+func mergeOverlay(base, overlay map[string]interface{}) map[string]interface{} {
+	if base == nil {
+		base = make(map[string]interface{}, len(overlay))
+	}
+	for k, v := range overlay {
+		key := k
+		for bk := range base {
+			if strings.EqualFold(bk, k) {
+				key = bk
+				break
+			}
+		}
+		if v == nil {
+			delete(base, key)
+			continue
+		}
+		sub, ok := v.(map[string]interface{})
+		baseSub, baseOK := base[key].(map[string]interface{})
+		if ok && baseOK {
+			base[key] = mergeOverlay(baseSub, sub)
+			continue
+		}
+		base[key] = v
+	}
+	return base
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeOverlay(t *testing.T) {
+	testData := []struct {
+		name    string
+		base    map[string]interface{}
+		overlay map[string]interface{}
+		wanted  map[string]interface{}
+	}{
+		{
+			name:    "no overlay",
+			base:    map[string]interface{}{"a": "1"},
+			overlay: nil,
+			wanted:  map[string]interface{}{"a": "1"},
+		},
+		{
+			name:    "no base",
+			base:    nil,
+			overlay: map[string]interface{}{"a": "1"},
+			wanted:  map[string]interface{}{"a": "1"},
+		},
+		{
+			name:    "nested objects",
+			base:    map[string]interface{}{"a": "1", "s": map[string]interface{}{"b": "2", "c": "3"}},
+			overlay: map[string]interface{}{"s": map[string]interface{}{"c": "4", "d": "5"}},
+			wanted:  map[string]interface{}{"a": "1", "s": map[string]interface{}{"b": "2", "c": "4", "d": "5"}},
+		},
+		{
+			name:    "null unsets",
+			base:    map[string]interface{}{"a": "1", "s": map[string]interface{}{"b": "2"}},
+			overlay: map[string]interface{}{"a": nil, "s": map[string]interface{}{"b": nil}, "x": nil},
+			wanted:  map[string]interface{}{"s": map[string]interface{}{}},
+		},
+		{
+			name:    "case insensitive keys",
+			base:    map[string]interface{}{"ListenPort": "1"},
+			overlay: map[string]interface{}{"listenPort": "2"},
+			wanted:  map[string]interface{}{"ListenPort": "2"},
+		},
+		{
+			name:    "value replaces object",
+			base:    map[string]interface{}{"s": map[string]interface{}{"b": "2"}},
+			overlay: map[string]interface{}{"s": []interface{}{"3"}},
+			wanted:  map[string]interface{}{"s": []interface{}{"3"}},
+		},
+	}
+
+	for _, test := range testData {
+		assert.Equal(t, test.wanted, mergeOverlay(test.base, test.overlay), test.name)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProfilesHappyPath(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{"listenPort": 9090, "log": {"level": "debug", "format": "json"}, "server": {"address": ":9000"}}`)
+	writeFile(t, filepath.Join(dir, "config.prod.yaml"), "log:\n  level: warn\nserver:\n  address: null\n")
+	writeFile(t, filepath.Join(dir, "config.staging.json"), `{"listenPort": 9091}`)
+
+	testData := []struct {
+		name     string
+		opts     Options
+		level    string
+		format   string
+		addr     string
+		settings map[string]Setting
+	}{
+		{
+			name:   "no profile",
+			level:  "debug",
+			format: "json",
+			addr:   ":9000",
+		},
+		{
+			name:   "environment",
+			opts:   Options{LookupEnv: mockEnv(map[string]string{"APP_PROFILE": "prod"})},
+			level:  "warn",
+			format: "json",
+			addr:   ":9090",
+			settings: map[string]Setting{
+				"log.level":      {Key: "log.level", Value: "warn", Source: SourceFile, Origin: filepath.Join(dir, "config.prod.yaml")},
+				"log.format":     {Key: "log.format", Value: "json", Source: SourceFile, Origin: filepath.Join(dir, configFileName)},
+				"server.address": {Key: "server.address", Value: "", Source: SourceDefault},
+			},
+		},
+		{
+			name:   "option",
+			opts:   Options{Profile: "staging", LookupEnv: mockEnv(map[string]string{"APP_PROFILE": "prod"})},
+			level:  "debug",
+			format: "json",
+			addr:   ":9000",
+			settings: map[string]Setting{
+				"listenPort": {Key: "listenPort", Value: 9091, Source: SourceFile, Origin: filepath.Join(dir, "config.staging.json")},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		opts := test.opts
+		opts.SearchPaths, opts.Args = []string{dir}, []string{}
+		if opts.LookupEnv == nil {
+			opts.LookupEnv = mockEnv(nil)
+		}
+		cfg, settings, err := LoadSettings(opts)
+		assert.Nil(t, err, test.name)
+		if err != nil {
+			continue
+		}
+		assert.Equal(t, test.level, cfg.Log.Level, test.name)
+		assert.Equal(t, test.format, cfg.Log.Format, test.name)
+		assert.Equal(t, test.addr, cfg.Addr(), test.name)
+		for _, s := range settings {
+			if wanted, ok := test.settings[s.Key]; ok {
+				assert.Equal(t, wanted, s, test.name)
+			}
+		}
+	}
+}
+
+func TestProfilesError(t *testing.T) {
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{}`)
+	writeFile(t, filepath.Join(dir, "config.bad.json"), `{"log": {"levle": "warn"}}`)
+	writeFile(t, filepath.Join(dir, "config.broken.json"), `{"log": `)
+
+	testData := []struct {
+		name    string
+		profile string
+	}{
+		{name: "missing overlay", profile: "prod"},
+		{name: "unknown key", profile: "bad"},
+		{name: "malformed overlay", profile: "broken"},
+		{name: "illegal profile", profile: "../config"},
+	}
+
+	for _, test := range testData {
+		_, err := Load(Options{SearchPaths: []string{dir}, Profile: test.profile, LookupEnv: mockEnv(nil), Args: []string{}})
+		assert.NotNil(t, err, test.name)
+	}
+}
+
+func TestWatchOverlay(t *testing.T) {
+	orgDelay := reloadDelay
+	reloadDelay = 10 * time.Millisecond
+	defer func() {
+		reloadDelay = orgDelay
+	}()
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{"listenPort": 8081}`)
+	overlay := filepath.Join(dir, "config.prod.json")
+	writeFile(t, overlay, `{}`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	_, err := Watch(ctx, Options{SearchPaths: []string{dir}, Profile: "prod", LookupEnv: mockEnv(nil), Args: []string{}})
+	assert.Nil(t, err, "watch")
+
+	writeFile(t, overlay, `{"listenPort": 9090}`)
+	deadline := time.Now().Add(5 * time.Second)
+	for Current().ListenPort != 9090 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 9090, Current().ListenPort, "overlay reloaded")
+}
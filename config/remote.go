@@ -0,0 +1,215 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const remoteCacheFile = configFileBase + ".remote.json"
+
+// maxRemoteSize is the largest remote config read, in bytes.
+var maxRemoteSize int64 = 10 << 20
+
+// Remote is a config file fetched from an HTTP endpoint, deep merged over
+// the local config file and its profile overlay. The last good copy is
+// cached on disk and used when the endpoint is unreachable.
+type Remote struct {
+	URL string
+	// Token is sent as a bearer token when set.
+	Token string
+	// Client fetches the config, a client with a 10s timeout when nil.
+	Client *http.Client
+	// CacheFile is the last good copy, config.remote.json next to the
+	// config file when empty.
+	CacheFile string
+	// PollInterval is the interval Watch polls the endpoint at, 30s when
+	// zero.
+	PollInterval time.Duration
+
+	// etag and data are the last fetched config which loaded
+	mu   sync.Mutex
+	etag string
+	data []byte
+}
+
+var defaultRemoteClient = &http.Client{Timeout: 10 * time.Second}
+
+// remoteFormats maps the media types of the fetched config to their
+// format, the extension of the URL selects it for other media types.
+var remoteFormats = map[string]string{
+	"application/json":   ".json",
+	"application/yaml":   ".yaml",
+	"application/x-yaml": ".yaml",
+	"text/yaml":          ".yaml",
+	"application/toml":   ".toml",
+}
+
+// fetch gets the config from the endpoint converted to JSON and its ETag,
+// b is nil when the config still matches etag.
+func (r *Remote) fetch(etag string) (b []byte, newETag string, err error) {
+	req, err := http.NewRequest("GET", r.URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if r.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.Token)
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	client := r.Client
+	if client == nil {
+		client = defaultRemoteClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && etag != "" {
+		return nil, etag, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("%s: %s", r.URL, resp.Status)
+	}
+	b, err = ioutil.ReadAll(io.LimitReader(resp.Body, maxRemoteSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(b)) > maxRemoteSize {
+		return nil, "", fmt.Errorf("%s: config larger than %d bytes", r.URL, maxRemoteSize)
+	}
+
+	f := formatOf(strings.SplitN(r.URL, "?", 2)[0])
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		if ext, ok := remoteFormats[mediaType]; ok {
+			f = formats[ext]
+		}
+	}
+	if b, err = f.toJSON(b); err != nil {
+		return nil, "", fmt.Errorf("%s: %v", r.URL, err)
+	}
+	if _, err := decodeObject(b); err != nil {
+		return nil, "", fmt.Errorf("%s: %v", r.URL, err)
+	}
+	return b, resp.Header.Get("ETag"), nil
+}
+
+func (r *Remote) loaded() (etag string, data []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.etag, r.data
+}
+
+// load fetches the config, or returns the cached copy in cache and logs
+// the error when the endpoint fails. commit makes the fetched config the
+// last good copy and caches it, once the config built from it is valid.
+func (r *Remote) load(cache string) (b []byte, commit func(), err error) {
+	etag, data := r.loaded()
+	b, etag, err = r.fetch(etag)
+	if err != nil {
+		cached, cacheErr := ioutil.ReadFile(cache)
+		if cacheErr != nil {
+			return nil, nil, fmt.Errorf("config: remote config: %v, no cached copy: %v", err, cacheErr)
+		}
+		logf("config: remote config: %v, using the cached copy %s", err, cache)
+		return cached, func() {}, nil
+	}
+	if b == nil {
+		return data, func() {}, nil
+	}
+
+	return b, func() {
+		r.mu.Lock()
+		r.etag, r.data = etag, b
+		r.mu.Unlock()
+		if cached, err := ioutil.ReadFile(cache); err != nil || string(cached) != string(b) {
+			if err := writeFileAtomic(cache, b, 0600); err != nil {
+				logf("config: cache remote config: %v", err)
+			}
+		}
+	}, nil
+}
+
+// cacheFile returns the cache of the remote config of the config file path.
+func (r *Remote) cacheFile(path string, opts Options) string {
+	if r.CacheFile != "" {
+		return r.CacheFile
+	}
+	if path != "" {
+		return filepath.Join(filepath.Dir(path), remoteCacheFile)
+	}
+	dirs := opts.SearchPaths
+	if dirs == nil {
+		dirs = DefaultSearchPaths()
+	}
+	if len(dirs) == 0 {
+		return remoteCacheFile
+	}
+	return filepath.Join(dirs[0], remoteCacheFile)
+}
+
+// poll notifies changed when the remote config changes, until done is closed.
+func (r *Remote) poll(done <-chan struct{}, changed chan<- struct{}) {
+	interval := r.PollInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	etag, last := r.loaded()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		select {
+		case <-done:
+			return
+		default:
+		}
+		b, newETag, err := r.fetch(etag)
+		if err != nil {
+			logf("config: remote config: %v", err)
+			continue
+		}
+		etag = newETag
+		if b != nil && string(b) != string(last) {
+			last = b
+			notify(changed)
+		}
+	}
+}
+
+// writeFileAtomic writes b to path through a temporary file, so readers
+// never see a partial file.
+func writeFileAtomic(path string, b []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
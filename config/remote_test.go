@@ -0,0 +1,182 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// remoteServer serves content with its ETag to the requests with the
+// bearer token "secret".
+type remoteServer struct {
+	sync.Mutex
+	content     string
+	contentType string
+	etag        string
+	requests    []*http.Request
+}
+
+func (s *remoteServer) set(content, etag string) {
+	s.Lock()
+	defer s.Unlock()
+	s.content, s.etag = content, etag
+}
+
+func (s *remoteServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Lock()
+	defer s.Unlock()
+	s.requests = append(s.requests, r)
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if s.etag != "" && r.Header.Get("If-None-Match") == s.etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	if s.contentType != "" {
+		w.Header().Set("Content-Type", s.contentType)
+	}
+	if s.etag != "" {
+		w.Header().Set("ETag", s.etag)
+	}
+	w.Write([]byte(s.content))
+}
+
+func TestRemoteHappyPath(t *testing.T) {
+	testData := []struct {
+		name        string
+		content     string
+		contentType string
+	}{
+		{
+			name:        "json",
+			content:     `{"listenPort": 9090, "log": {"level": "debug"}}`,
+			contentType: "application/json; charset=utf-8",
+		},
+		{
+			name:        "yaml",
+			content:     "listenPort: 9090\nlog:\n  level: debug\n",
+			contentType: "application/yaml",
+		},
+	}
+
+	for _, test := range testData {
+		srv := &remoteServer{content: test.content, contentType: test.contentType, etag: `"v1"`}
+		ts := httptest.NewServer(srv)
+		dir := t.TempDir()
+		writeConfigFile(t, dir, `{"listenPort": 8081, "log": {"format": "json"}}`)
+		remote := &Remote{URL: ts.URL + "/config", Token: "secret"}
+		opts := Options{SearchPaths: []string{dir}, Remote: remote, LookupEnv: mockEnv(nil), Args: []string{}}
+
+		cfg, settings, err := LoadSettings(opts)
+		assert.Nil(t, err, test.name)
+		if err != nil {
+			ts.Close()
+			continue
+		}
+		assert.Equal(t, 9090, cfg.ListenPort, test.name)
+		assert.Equal(t, "debug", cfg.Log.Level, test.name)
+		assert.Equal(t, "json", cfg.Log.Format, test.name)
+		assert.Equal(t, Setting{Key: "listenPort", Value: 9090, Source: SourceFile, Origin: remote.URL}, settings[0], test.name)
+
+		cached, err := ioutil.ReadFile(filepath.Join(dir, "config.remote.json"))
+		assert.Nil(t, err, test.name)
+		assert.JSONEq(t, `{"listenPort": 9090, "log": {"level": "debug"}}`, string(cached), test.name)
+
+		// the second load is a conditional request
+		cfg, err = Load(opts)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, 9090, cfg.ListenPort, test.name)
+		assert.Equal(t, 2, len(srv.requests), test.name)
+		assert.Equal(t, `"v1"`, srv.requests[1].Header.Get("If-None-Match"), test.name)
+		ts.Close()
+	}
+}
+
+func TestRemoteFallback(t *testing.T) {
+	orgLogf := logf
+	defer func() {
+		logf = orgLogf
+	}()
+	var logged []string
+	logf = func(format string, v ...interface{}) {
+		logged = append(logged, format)
+	}
+
+	srv := &remoteServer{content: `{"listenPort": 9090}`}
+	ts := httptest.NewServer(srv)
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{}`)
+	cache := filepath.Join(t.TempDir(), "remote.json")
+	opts := Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(nil), Args: []string{}}
+
+	// no cached copy yet
+	opts.Remote = &Remote{URL: ts.URL, Token: "wrong", CacheFile: cache}
+	_, err := Load(opts)
+	assert.NotNil(t, err, "unauthorized without cache")
+
+	opts.Remote = &Remote{URL: ts.URL, Token: "secret", CacheFile: cache}
+	cfg, err := Load(opts)
+	assert.Nil(t, err, "fetched")
+	assert.Equal(t, 9090, cfg.ListenPort, "fetched")
+	assert.Empty(t, logged, "fetched")
+
+	srv.set(`{"listenPort": "http"`, "")
+	cfg, err = Load(opts)
+	assert.Nil(t, err, "malformed remote config")
+	assert.Equal(t, 9090, cfg.ListenPort, "malformed remote config")
+
+	orgMax := maxRemoteSize
+	maxRemoteSize = 16
+	srv.set(`{"listenPort": 8080}`, "")
+	cfg, err = Load(opts)
+	maxRemoteSize = orgMax
+	assert.Nil(t, err, "oversized remote config")
+	assert.Equal(t, 9090, cfg.ListenPort, "oversized remote config")
+
+	srv.set(`{"listenPort": 0}`, "")
+	_, err = Load(opts)
+	assert.NotNil(t, err, "invalid remote config")
+	cached, err := ioutil.ReadFile(cache)
+	assert.Nil(t, err, "invalid remote config")
+	assert.JSONEq(t, `{"listenPort": 9090}`, string(cached), "invalid remote config not cached")
+
+	ts.Close()
+	cfg, err = Load(opts)
+	assert.Nil(t, err, "unreachable")
+	assert.Equal(t, 9090, cfg.ListenPort, "unreachable")
+	assert.Equal(t, 3, len(logged), "fallbacks logged")
+}
+
+func TestWatchRemote(t *testing.T) {
+	orgDelay := reloadDelay
+	reloadDelay = 10 * time.Millisecond
+	defer func() {
+		reloadDelay = orgDelay
+	}()
+	srv := &remoteServer{content: `{"listenPort": 8081}`, etag: `"v1"`}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	remote := &Remote{URL: ts.URL, Token: "secret", PollInterval: 10 * time.Millisecond}
+	_, err := Watch(ctx, Options{SearchPaths: []string{t.TempDir()}, Remote: remote, LookupEnv: mockEnv(nil), Args: []string{}})
+	assert.Nil(t, err, "watch")
+	assert.Equal(t, 8081, Current().ListenPort, "watch")
+
+	srv.set(`{"listenPort": 9090}`, `"v2"`)
+	deadline := time.Now().Add(5 * time.Second)
+	for Current().ListenPort != 9090 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, 9090, Current().ListenPort, "remote reloaded")
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrUnknownResolver = errors.New("unknown resolver")
+	ErrBadReference    = errors.New("bad reference")
+	ErrEnvNotSet       = errors.New("environment variable not set")
+)
+
+// Resolver returns the value referenced by ref in the "${scheme:ref}"
+// values of the config file.
+type Resolver func(ref string) (string, error)
+
+var resolvers = struct {
+	sync.RWMutex
+	m map[string]Resolver
+}{m: make(map[string]Resolver)}
+
+// RegisterResolver registers r for the references of scheme, replacing the
+// resolver registered before, if any.
+func RegisterResolver(scheme string, r Resolver) {
+	resolvers.Lock()
+	defer resolvers.Unlock()
+	resolvers.m[scheme] = r
+}
+
+func lookupResolver(scheme string) (Resolver, bool) {
+	resolvers.RLock()
+	defer resolvers.RUnlock()
+	r, ok := resolvers.m[scheme]
+	return r, ok
+}
+
+func init() {
+	RegisterResolver("env", resolveEnv)
+	RegisterResolver("file", resolveFile)
+}
+
+// resolveEnv returns the value of the environment variable name.
+func resolveEnv(name string) (string, error) {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrEnvNotSet, name)
+	}
+	return v, nil
+}
+
+// resolveFile returns the content of the file path without its trailing
+// newline, like the secrets mounted by docker and kubernetes.
+func resolveFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// expand replaces the "${scheme:ref}" references of s by the value of
+// their resolver, "$${" is a literal "${".
+func expand(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		b.WriteString(s[:i])
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("%w: %s", ErrBadReference, s[i:])
+		}
+		ref := s[i+2 : i+end]
+		s = s[i+end+1:]
+		colon := strings.IndexByte(ref, ':')
+		if colon < 0 {
+			return "", fmt.Errorf("%w: ${%s}", ErrBadReference, ref)
+		}
+		r, ok := lookupResolver(ref[:colon])
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnknownResolver, ref[:colon])
+		}
+		v, err := r(ref[colon+1:])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+}
+
+// expandRefs expands the references of the strings of the decoded file v.
+func expandRefs(v interface{}, path string) (interface{}, []FieldError) {
+	var errs []FieldError
+	switch v := v.(type) {
+	case string:
+		s, err := expand(v)
+		if err != nil {
+			return v, []FieldError{{Path: path, Message: err.Error()}}
+		}
+		return s, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			e, key := v[k], k
+			if path != "" {
+				key = path + "." + k
+			}
+			n, es := expandRefs(e, key)
+			v[k], errs = n, append(errs, es...)
+		}
+	case []interface{}:
+		for i, e := range v {
+			n, es := expandRefs(e, fmt.Sprintf("%s[%d]", path, i))
+			v[i], errs = n, append(errs, es...)
+		}
+	}
+	return v, errs
+}
+
+// redacted replaces the value of a Secret in dumps.
+const redacted = "[REDACTED]"
+
+// Secret is a string setting, a password or a token, that is redacted when
+// formatted or marshaled. Value returns the secret itself.
+type Secret string
+
+// Value returns the secret.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// Format redacts the secret for every verb.
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		fmt.Fprintf(f, "%q", s.String())
+		return
+	}
+	fmt.Fprintf(f, fmt.FormatString(f, 's'), s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandHappyPath(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "s3cret")
+	secretFile := filepath.Join(t.TempDir(), "db")
+	if err := ioutil.WriteFile(secretFile, []byte("from file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	RegisterResolver("upper", func(ref string) (string, error) {
+		return strings.ToUpper(ref), nil
+	})
+
+	testData := []struct {
+		name   string
+		input  string
+		wanted string
+	}{
+		{name: "no reference", input: "plain", wanted: "plain"},
+		{name: "env", input: "${env:DB_PASSWORD}", wanted: "s3cret"},
+		{name: "file", input: "${file:" + secretFile + "}", wanted: "from file"},
+		{name: "embedded", input: "user:${env:DB_PASSWORD}@db", wanted: "user:s3cret@db"},
+		{name: "registered resolver", input: "${upper:abc}-${upper:d}", wanted: "ABC-D"},
+		{name: "escaped", input: "$${env:DB_PASSWORD}", wanted: "${env:DB_PASSWORD}"},
+		{name: "lone dollar", input: "$5 ${env:DB_PASSWORD}", wanted: "$5 s3cret"},
+	}
+
+	for _, test := range testData {
+		res, err := expand(test.input)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wanted, res, test.name)
+	}
+}
+
+func TestExpandError(t *testing.T) {
+	testData := []struct {
+		name   string
+		input  string
+		wanted error
+	}{
+		{name: "unknown resolver", input: "${vault:db}", wanted: ErrUnknownResolver},
+		{name: "missing scheme", input: "${DB_PASSWORD}", wanted: ErrBadReference},
+		{name: "unterminated", input: "${env:DB_PASSWORD", wanted: ErrBadReference},
+		{name: "unset env", input: "${env:CONFIG_TEST_UNSET}", wanted: ErrEnvNotSet},
+	}
+
+	for _, test := range testData {
+		_, err := expand(test.input)
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+	}
+}
+
+func TestLoadReferences(t *testing.T) {
+	t.Setenv("LOG_OUTPUT", "/var/log/users.log")
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{"log": {"output": "${env:LOG_OUTPUT}"}, "server": {"address": "${env:CONFIG_TEST_UNSET}"}}`)
+	_, err := Load(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(nil), Args: []string{}})
+	var errs ValidationErrors
+	assert.True(t, errors.As(err, &errs), "unset env")
+	assert.Equal(t, ValidationErrors{{Path: "server.address", Message: "environment variable not set: CONFIG_TEST_UNSET"}}, errs, "unset env")
+
+	writeConfigFile(t, dir, `{"log": {"output": "${env:LOG_OUTPUT}"}}`)
+	cfg, err := Load(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(nil), Args: []string{}})
+	assert.Nil(t, err, "expanded")
+	assert.Equal(t, "/var/log/users.log", cfg.Log.Output, "expanded")
+}
+
+func TestSecretRedacted(t *testing.T) {
+	s := Secret("s3cret")
+	assert.Equal(t, "s3cret", s.Value(), "value")
+
+	b, err := json.Marshal(struct {
+		Password Secret `json:"password"`
+	}{s})
+	assert.Nil(t, err, "json")
+	assert.Equal(t, `{"password":"[REDACTED]"}`, string(b), "json")
+
+	for _, format := range []string{"%v", "%s", "%+v", "%#v", "%d", "%x", "%X", "%q", "%10s"} {
+		assert.NotContains(t, fmt.Sprintf(format, s), "s3cret", format)
+		assert.NotContains(t, fmt.Sprintf(format, struct{ Password Secret }{s}), "s3cret", format)
+	}
+	assert.Equal(t, `"[REDACTED]"`, fmt.Sprintf("%#v", s), "go syntax")
+
+	var buf bytes.Buffer
+	assert.Nil(t, PrintSettings(&buf, []Setting{{Key: "db.password", Value: s, Source: SourceEnv, Origin: "APP_DB_PASSWORD"}}), "print")
+	assert.Equal(t, "db.password  [REDACTED]  env APP_DB_PASSWORD\n", buf.String(), "print")
+
+	assert.Equal(t, "", Secret("").String(), "empty")
+}
+
+func TestSecretSettings(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "s3cret")
+	dir := t.TempDir()
+	writeConfigFile(t, dir, `{"database": {"user": "app", "password": "${env:DB_PASSWORD}"}}`)
+	opts := Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(nil), Args: []string{}}
+
+	cfg, settings, err := LoadSettings(opts)
+	if !assert.Nil(t, err, "load") {
+		return
+	}
+	assert.Equal(t, "s3cret", cfg.Database.Password.Value(), "load")
+	var buf bytes.Buffer
+	assert.Nil(t, PrintSettings(&buf, settings), "print")
+	assert.Contains(t, buf.String(), "database.password ", "print")
+	assert.Contains(t, buf.String(), " [REDACTED] ", "print")
+	assert.NotContains(t, buf.String(), "s3cret", "print")
+
+	diffs, err := DiffDefaults(opts)
+	assert.Nil(t, err, "diff")
+	assert.Contains(t, diffs, Difference{Key: "database.password", Default: Secret(""), Value: Secret("s3cret")}, "diff")
+	assert.NotContains(t, fmt.Sprintf("%v %+v", diffs, diffs), "s3cret", "diff")
+}
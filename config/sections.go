@@ -0,0 +1,120 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServerConfig holds the settings of the HTTP server.
+type ServerConfig struct {
+	Address           string    `json:"address" doc:"listen address (host:port), :<listenPort> when empty"`
+	ReadTimeout       Duration  `json:"readTimeout" default:"10s" validate:"min=0s" doc:"max duration of reading a request, 0 for none"`
+	ReadHeaderTimeout Duration  `json:"readHeaderTimeout" default:"5s" validate:"min=0s" doc:"max duration of reading the request headers, 0 for readTimeout"`
+	WriteTimeout      Duration  `json:"writeTimeout" default:"30s" validate:"min=0s" doc:"max duration of writing a response, 0 for none"`
+	IdleTimeout       Duration  `json:"idleTimeout" default:"2m" validate:"min=0s" doc:"max duration of an idle keep-alive connection, 0 for readTimeout"`
+	MaxHeaderBytes    int       `json:"maxHeaderBytes" default:"1048576" validate:"min=0" doc:"max size of the request headers in bytes"`
+	TLS               TLSConfig `json:"tls" doc:"HTTPS is served when both files are set"`
+}
+
+// TLSConfig holds the certificate of the HTTPS server.
+type TLSConfig struct {
+	CertFile string `json:"certFile" validate:"file" doc:"PEM certificate chain"`
+	KeyFile  string `json:"keyFile" validate:"file" doc:"PEM private key"`
+}
+
+// Enabled reports whether the server serves HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// LogConfig holds the settings of the logger.
+type LogConfig struct {
+	Level  string `json:"level" default:"info" validate:"required,oneof=debug info warn error" doc:"debug, info, warn or error"`
+	Format string `json:"format" default:"text" validate:"required,oneof=text json" doc:"text or json"`
+	Output string `json:"output" default:"stderr" validate:"required" doc:"stdout, stderr or the path of a file"`
+}
+
+// CacheConfig holds the settings of the caches.
+type CacheConfig struct {
+	Size int                 `json:"size" default:"1024" validate:"min=1" doc:"max number of entries of a cache"`
+	TTL  Duration            `json:"ttl" default:"5m" validate:"min=0s" doc:"lifetime of the entries, 0 for no expiry"`
+	TTLs map[string]Duration `json:"ttls" default:"" doc:"lifetime of the entries of the named caches, overriding ttl"`
+}
+
+// TTLOf returns the lifetime of the entries of the cache name.
+func (c CacheConfig) TTLOf(name string) time.Duration {
+	if ttl, ok := c.TTLs[name]; ok {
+		return time.Duration(ttl)
+	}
+	return time.Duration(c.TTL)
+}
+
+// DatabaseConfig holds the credentials of the database.
+type DatabaseConfig struct {
+	User     string `json:"user" doc:"user of the database"`
+	Password Secret `json:"password" doc:"password of the database, use a reference such as ${env:DB_PASSWORD}"`
+}
+
+// MiddlewareConfig toggles and tunes the middlewares.
+type MiddlewareConfig struct {
+	Gzip           bool  `json:"gzip" default:"true" doc:"compress the responses"`
+	GzipLevel      int   `json:"gzipLevel" default:"-1" validate:"min=-2,max=9" doc:"compress/gzip level, -1 for the default, -2 for Huffman only"`
+	BodyLimit      int64 `json:"bodyLimit" default:"10485760" validate:"min=0" doc:"max size of a request body in bytes, 0 for no limit"`
+	MultipartLimit int64 `json:"multipartLimit" default:"33554432" validate:"min=0" doc:"max size of a multipart request body in bytes, 0 for bodyLimit"`
+}
+
+// Addr returns the listen address of the server.
+func (c *Config) Addr() string {
+	if c.Server.Address != "" {
+		return c.Server.Address
+	}
+	return ":" + strconv.Itoa(c.ListenPort)
+}
+
+// Duration is a time.Duration written as a string, "1m30s", in config
+// files.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// configDocs returns the doc tags of the settings and sections of t by json
+// path.
+func configDocs(t reflect.Type, prefix string) map[string]string {
+	docs := make(map[string]string)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if doc := f.Tag.Get("doc"); doc != "" {
+			docs[prefix+name] = doc
+		}
+		if isSection(f.Type) {
+			for k, doc := range configDocs(f.Type, prefix+name+".") {
+				docs[k] = doc
+			}
+		}
+	}
+	return docs
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSectionsHappyPath(t *testing.T) {
+	testData := []struct {
+		name    string
+		content string
+		env     map[string]string
+		args    []string
+		check   func(cfg *Config) bool
+	}{
+		{
+			name:    "defaults",
+			content: `{}`,
+			check: func(cfg *Config) bool {
+				return cfg.Addr() == ":8080" && cfg.Server.ReadTimeout == Duration(10*time.Second) &&
+					cfg.Log.Level == "info" && cfg.Cache.TTLOf("users") == 5*time.Minute &&
+					cfg.Middleware.Gzip && !cfg.Server.TLS.Enabled()
+			},
+		},
+		{
+			name:    "file",
+			content: `{"server": {"address": "127.0.0.1:9000", "writeTimeout": "1m30s"}, "cache": {"ttls": {"users": "1h"}}}`,
+			check: func(cfg *Config) bool {
+				return cfg.Addr() == "127.0.0.1:9000" && cfg.Server.WriteTimeout == Duration(90*time.Second) &&
+					cfg.Cache.TTLOf("users") == time.Hour && cfg.Cache.TTLOf("items") == 5*time.Minute
+			},
+		},
+		{
+			name:    "environment",
+			content: `{}`,
+			env:     map[string]string{"APP_SERVER_READ_TIMEOUT": "1m", "APP_CACHE_TTLS": "users=1s, items=2s", "APP_LOG_LEVEL": "debug"},
+			check: func(cfg *Config) bool {
+				return cfg.Server.ReadTimeout == Duration(time.Minute) && cfg.Log.Level == "debug" &&
+					cfg.Cache.TTLOf("users") == time.Second && cfg.Cache.TTLOf("items") == 2*time.Second
+			},
+		},
+		{
+			name:    "flags",
+			content: `{}`,
+			args:    []string{"--middleware.gzip-level=9", "--middleware.gzip=false", "--server.tls.cert-file", "sections.go", "--server.tls.key-file=sections.go"},
+			check: func(cfg *Config) bool {
+				return cfg.Middleware.GzipLevel == 9 && !cfg.Middleware.Gzip && cfg.Server.TLS.Enabled()
+			},
+		},
+	}
+
+	for _, test := range testData {
+		dir := t.TempDir()
+		writeConfigFile(t, dir, test.content)
+		args := test.args
+		if args == nil {
+			args = []string{}
+		}
+		cfg, err := Load(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(test.env), Args: args})
+		assert.Nil(t, err, test.name)
+		if err == nil {
+			assert.True(t, test.check(cfg), test.name)
+		}
+	}
+}
+
+func TestSectionsError(t *testing.T) {
+	testData := []struct {
+		name    string
+		content string
+		env     map[string]string
+		wanted  ValidationErrors
+	}{
+		{
+			name:    "bad duration",
+			content: `{"server": {"readTimeout": "soon"}}`,
+			wanted:  ValidationErrors{{Path: "server.readTimeout", Message: `time: invalid duration "soon"`}},
+		},
+		{
+			name:    "negative duration",
+			content: `{"cache": {"ttl": "-1s"}}`,
+			wanted:  ValidationErrors{{Path: "cache.ttl", Message: "must be at least 0s"}},
+		},
+		{
+			name:    "enums and ranges",
+			content: `{"log": {"level": "trace"}, "middleware": {"gzipLevel": 10}}`,
+			wanted: ValidationErrors{
+				{Path: "log.level", Message: `must be one of debug, info, warn, error, got "trace"`},
+				{Path: "middleware.gzipLevel", Message: "must be at most 9"},
+			},
+		},
+		{
+			name:    "missing tls file",
+			content: `{"server": {"tls": {"certFile": "missing.pem"}}}`,
+			wanted:  ValidationErrors{{Path: "server.tls.certFile", Message: "file not found: missing.pem"}},
+		},
+		{
+			name:    "unknown nested key",
+			content: `{"server": {"readTimout": "1s"}}`,
+			wanted:  ValidationErrors{{Path: "server.readTimout", Message: `unknown key, did you mean "readTimeout"?`}},
+		},
+		{
+			name:    "bad map entry",
+			content: `{}`,
+			env:     map[string]string{"APP_CACHE_TTLS": "users"},
+			wanted:  ValidationErrors{{Path: "cache.ttls", Message: `APP_CACHE_TTLS: missing = in "users"`}},
+		},
+	}
+
+	for _, test := range testData {
+		dir := t.TempDir()
+		writeConfigFile(t, dir, test.content)
+		_, err := Load(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(test.env), Args: []string{}})
+		var errs ValidationErrors
+		assert.True(t, errors.As(err, &errs), test.name)
+		assert.Equal(t, test.wanted, errs, test.name)
+	}
+}
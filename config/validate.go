@@ -0,0 +1,223 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FieldError is a problem of the setting at the json path Path.
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors lists all the problems of a config.
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return "config: " + strings.Join(msgs, "; ")
+}
+
+// checkRules checks the value v of f against the rules of its validate tag
+// (validate:"required,min=1,max=65535"):
+//
+//	required    the value is not empty
+//	min, max    bounds of numbers and durations, or of the length of
+//	            strings, slices and maps
+//	oneof       the value is one of the space separated values
+//	duration    the string is a time.Duration
+//	file        the string is the path of an existing file
+//
+// Empty values are only checked by the required, min and max rules.
+func checkRules(f field, v reflect.Value) []FieldError {
+	var errs []FieldError
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, FieldError{Path: f.key, Message: fmt.Sprintf(format, args...)})
+	}
+	for _, rule := range strings.Split(f.validate, ",") {
+		key, value := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			key, value = rule[:i], rule[i+1:]
+		}
+		if key == "" {
+			continue
+		}
+		if key == "required" {
+			if v.IsZero() {
+				fail("is required")
+			}
+			continue
+		}
+		if v.IsZero() && key != "min" && key != "max" {
+			continue
+		}
+		switch key {
+		case "min", "max":
+			n, bound, err := compare(v, value)
+			if err != nil {
+				fail("bad %s rule: %v", key, err)
+			} else if key == "min" && n < 0 {
+				fail("must be at least %s", bound)
+			} else if key == "max" && n > 0 {
+				fail("must be at most %s", bound)
+			}
+		case "oneof":
+			s := fmt.Sprint(v.Interface())
+			values := strings.Fields(value)
+			found := false
+			for _, o := range values {
+				found = found || o == s
+			}
+			if !found {
+				fail("must be one of %s, got %q", strings.Join(values, ", "), s)
+			}
+		case "duration":
+			if _, err := time.ParseDuration(v.String()); err != nil {
+				fail("invalid duration %q", v.String())
+			}
+		case "file":
+			fi, err := os.Stat(v.String())
+			if err != nil {
+				fail("file not found: %s", v.String())
+			} else if fi.IsDir() {
+				fail("%s is a directory", v.String())
+			}
+		default:
+			fail("unknown rule %q", key)
+		}
+	}
+	return errs
+}
+
+// compare compares v, or its length, with bound and returns -1, 0 or 1 and
+// the bound to report.
+func compare(v reflect.Value, bound string) (int, string, error) {
+	sign := func(d float64) int {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return 1
+		}
+		return 0
+	}
+	if v.Type() == durationType || v.Type() == configDurationType {
+		d, err := time.ParseDuration(bound)
+		if err != nil {
+			return 0, "", err
+		}
+		return sign(float64(time.Duration(v.Int()) - d)), d.String(), nil
+	}
+	b, err := strconv.ParseFloat(bound, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return sign(float64(v.Int()) - b), bound, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return sign(float64(v.Uint()) - b), bound, nil
+	case reflect.Float32, reflect.Float64:
+		return sign(v.Float() - b), bound, nil
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		return sign(float64(v.Len()) - b), bound + " long", nil
+	}
+	return 0, "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// unknownKeys reports the keys of the decoded file obj that are not
+// settings of the struct t, with the closest setting name as a suggestion.
+func unknownKeys(obj map[string]interface{}, t reflect.Type, prefix string) []FieldError {
+	fields := make(map[string]reflect.StructField)
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if f.PkgPath != "" || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[strings.ToLower(name)] = f
+		names = append(names, name)
+	}
+
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var errs []FieldError
+	for _, k := range keys {
+		f, ok := fields[strings.ToLower(k)]
+		if !ok {
+			msg := "unknown key"
+			if s := suggest(k, names); s != "" {
+				msg += fmt.Sprintf(", did you mean %q?", s)
+			}
+			errs = append(errs, FieldError{Path: prefix + k, Message: msg})
+			continue
+		}
+		if sub, ok := obj[k].(map[string]interface{}); ok && isSection(f.Type) {
+			errs = append(errs, unknownKeys(sub, f.Type, prefix+k+".")...)
+		}
+	}
+	return errs
+}
+
+// suggest returns the name closest to key, or "" when none is close
+// enough.
+func suggest(key string, names []string) string {
+	best, bestDist := "", len(key)/2+1
+	for _, name := range names {
+		if d := distance(strings.ToLower(key), strings.ToLower(name)); d < bestDist {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance of a and b.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
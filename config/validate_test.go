@@ -0,0 +1,188 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ruleConfig struct {
+	Name    string            `json:"name" validate:"required,min=3,max=8"`
+	Level   string            `json:"level" validate:"oneof=debug info"`
+	Workers int               `json:"workers" validate:"min=1"`
+	Ratio   float64           `json:"ratio" validate:"max=1"`
+	Timeout time.Duration     `json:"timeout" validate:"min=1s,max=1m"`
+	Delay   string            `json:"delay" validate:"duration"`
+	CAFile  string            `json:"caFile" validate:"file"`
+	Tags    []string          `json:"tags" validate:"max=2"`
+	Hosts   map[string]string `json:"hosts" validate:"required"`
+	Log     struct {
+		Format string `json:"format" validate:"oneof=text json"`
+	} `json:"log"`
+}
+
+func checkConfig(cfg *ruleConfig) []FieldError {
+	v := reflect.ValueOf(cfg).Elem()
+	var errs []FieldError
+	for _, f := range configFields(v.Type(), nil, "", "", "") {
+		errs = append(errs, checkRules(f, v.FieldByIndex(f.index))...)
+	}
+	return errs
+}
+
+func TestCheckRules(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	if err := ioutil.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	valid := func() *ruleConfig {
+		cfg := &ruleConfig{Name: "users", Level: "info", Workers: 2, Ratio: 0.5,
+			Timeout: time.Second, Delay: "1ms", CAFile: file, Hosts: map[string]string{"a": "b"}}
+		cfg.Log.Format = "json"
+		return cfg
+	}
+	testData := []struct {
+		name   string
+		update func(cfg *ruleConfig)
+		wanted []FieldError
+	}{
+		{
+			name:   "valid",
+			update: func(cfg *ruleConfig) {},
+		},
+		{
+			name:   "empty optional values",
+			update: func(cfg *ruleConfig) { cfg.Level, cfg.Delay, cfg.CAFile, cfg.Log.Format = "", "", "", "" },
+		},
+		{
+			name:   "required",
+			update: func(cfg *ruleConfig) { cfg.Name, cfg.Hosts = "", nil },
+			wanted: []FieldError{
+				{Path: "name", Message: "is required"},
+				{Path: "name", Message: "must be at least 3 long"},
+				{Path: "hosts", Message: "is required"},
+			},
+		},
+		{
+			name: "bounds",
+			update: func(cfg *ruleConfig) {
+				cfg.Name, cfg.Workers, cfg.Ratio, cfg.Timeout, cfg.Tags = "users-api", 0, 1.5, time.Hour, []string{"a", "b", "c"}
+			},
+			wanted: []FieldError{
+				{Path: "name", Message: "must be at most 8 long"},
+				{Path: "workers", Message: "must be at least 1"},
+				{Path: "ratio", Message: "must be at most 1"},
+				{Path: "timeout", Message: "must be at most 1m0s"},
+				{Path: "tags", Message: "must be at most 2 long"},
+			},
+		},
+		{
+			name: "formats",
+			update: func(cfg *ruleConfig) {
+				cfg.Level, cfg.Delay, cfg.CAFile, cfg.Log.Format = "trace", "soon", file+".missing", "xml"
+			},
+			wanted: []FieldError{
+				{Path: "level", Message: `must be one of debug, info, got "trace"`},
+				{Path: "delay", Message: `invalid duration "soon"`},
+				{Path: "caFile", Message: "file not found: " + file + ".missing"},
+				{Path: "log.format", Message: `must be one of text, json, got "xml"`},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		cfg := valid()
+		test.update(cfg)
+		assert.Equal(t, test.wanted, checkConfig(cfg), test.name)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	testData := []struct {
+		name   string
+		obj    map[string]interface{}
+		wanted []FieldError
+	}{
+		{
+			name: "known keys",
+			obj:  map[string]interface{}{"NAME": "a", "log": map[string]interface{}{"format": "text"}, "hosts": map[string]interface{}{"any": "b"}},
+		},
+		{
+			name: "suggestions",
+			obj:  map[string]interface{}{"nmae": "a", "timeuot": "1s", "log": map[string]interface{}{"formt": "text"}},
+			wanted: []FieldError{
+				{Path: "log.formt", Message: `unknown key, did you mean "format"?`},
+				{Path: "nmae", Message: `unknown key, did you mean "name"?`},
+				{Path: "timeuot", Message: `unknown key, did you mean "timeout"?`},
+			},
+		},
+		{
+			name: "no close key",
+			obj:  map[string]interface{}{"database": "a"},
+			wanted: []FieldError{
+				{Path: "database", Message: "unknown key"},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		assert.Equal(t, test.wanted, unknownKeys(test.obj, reflect.TypeOf(ruleConfig{}), ""), test.name)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	testData := []struct {
+		name    string
+		content string
+		env     map[string]string
+		wanted  ValidationErrors
+	}{
+		{
+			name:    "empty file",
+			content: `{}`,
+		},
+		{
+			name:    "zero port",
+			content: `{"listenPort": 0}`,
+			wanted:  ValidationErrors{{Path: "listenPort", Message: "must be at least 1"}},
+		},
+		{
+			name:    "all problems",
+			content: `{"listenPort": 70000, "listenPrt": 1}`,
+			wanted: ValidationErrors{
+				{Path: "listenPrt", Message: `unknown key, did you mean "listenPort"?`},
+				{Path: "listenPort", Message: "must be at most 65535"},
+			},
+		},
+		{
+			name:    "wrong type",
+			content: `{"listenPort": "http"}`,
+			wanted:  ValidationErrors{{Path: "listenPort", Message: "expected int, got string"}},
+		},
+		{
+			name:    "environment",
+			content: `{}`,
+			env:     map[string]string{"APP_LISTEN_PORT": "-1"},
+			wanted:  ValidationErrors{{Path: "listenPort", Message: "must be at least 1"}},
+		},
+	}
+
+	for _, test := range testData {
+		dir := t.TempDir()
+		writeConfigFile(t, dir, test.content)
+		_, err := Load(Options{SearchPaths: []string{dir}, LookupEnv: mockEnv(test.env), Args: []string{}})
+		if test.wanted == nil {
+			assert.Nil(t, err, test.name)
+			continue
+		}
+		var errs ValidationErrors
+		assert.True(t, errors.As(err, &errs), test.name)
+		assert.Equal(t, test.wanted, errs, test.name)
+	}
+}
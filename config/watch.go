@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var ErrNoConfigFile = errors.New("config: no config file to watch")
+
+var (
+	current atomic.Value
+
+	subscribersMu sync.Mutex
+	subscribers   []func(old, new *Config)
+
+	reloadMu sync.Mutex
+)
+
+var (
+	// pollInterval is the interval of the polling watcher.
+	pollInterval = 2 * time.Second
+	// reloadDelay groups the events of a single save into one reload.
+	reloadDelay = 100 * time.Millisecond
+
+	logf = log.Printf
+)
+
+// Current returns the active config. Unlike ConfigParams, it is safe to call
+// while Watch reloads the config.
+func Current() *Config {
+	cfg, _ := current.Load().(*Config)
+	if cfg == nil {
+		return ConfigParams
+	}
+	return cfg
+}
+
+// OnChange registers fn to be called with the previous and the new config
+// after each reload that changes the config.
+func OnChange(fn func(old, new *Config)) {
+	subscribersMu.Lock()
+	defer subscribersMu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Watch loads the config like Load, then reloads it until ctx is done when
+// the config file, its profile overlay or the remote config changes or the
+// process receives SIGHUP. A config that fails to load is logged and the
+// previous config stays active. It fails with ErrNoConfigFile when there is
+// neither a config file nor a remote config.
+func Watch(ctx context.Context, opts Options) (*Config, error) {
+	b, path, err := readFile(opts)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" && opts.Remote == nil {
+		return nil, ErrNoConfigFile
+	}
+	cfg, _, err := setConfig(b, path, opts)
+	if err != nil {
+		return nil, err
+	}
+	markLoaded()
+	opts.Path = path
+
+	var files []string
+	if path != "" {
+		files = append(files, path)
+	}
+	if overlay, _ := overlayFile(path, opts); overlay != "" {
+		files = append(files, overlay)
+	}
+	changed := make(chan struct{}, 1)
+	for _, file := range files {
+		if err := watchFile(ctx, file, changed); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Remote != nil {
+		go opts.Remote.poll(ctx.Done(), changed)
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+			case <-changed:
+				time.Sleep(reloadDelay)
+				select {
+				case <-changed:
+				default:
+				}
+			}
+			reload(opts)
+		}
+	}()
+	return cfg, nil
+}
+
+// reload reads the config file of opts again and notifies the subscribers
+// when the config changed.
+func reload(opts Options) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	old := Current()
+	b := []byte("{}")
+	if opts.Path != "" {
+		var err error
+		if b, err = readConfigFile(opts.Path); err != nil {
+			logf("config: reload %s: %v", opts.Path, err)
+			return
+		}
+	}
+	// ConfigParams is read without locking, only current is swapped
+	cfg, _, err := build(b, opts.Path, opts)
+	if err != nil {
+		logf("config: reload %s: %v", opts.Path, err)
+		return
+	}
+	current.Store(cfg)
+	if reflect.DeepEqual(old, cfg) {
+		return
+	}
+
+	subscribersMu.Lock()
+	fns := append([]func(old, new *Config){}, subscribers...)
+	subscribersMu.Unlock()
+	for _, fn := range fns {
+		fn(old, cfg)
+	}
+}
+
+// notify sends on changed without blocking, a pending send already
+// triggers a reload.
+func notify(changed chan<- struct{}) {
+	select {
+	case changed <- struct{}{}:
+	default:
+	}
+}
+
+// pollFile notifies changed when the modification time or the size of path
+// changes, until ctx is done.
+func pollFile(ctx context.Context, path string, changed chan<- struct{}) {
+	stat := func() (time.Time, int64) {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, -1
+		}
+		return fi.ModTime(), fi.Size()
+	}
+	modTime, size := stat()
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			t, s := stat()
+			if !t.Equal(modTime) || s != size {
+				modTime, size = t, s
+				notify(changed)
+			}
+		}
+	}()
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// watchFile notifies changed when path is written or replaced, until ctx is
+// done. It watches the directory of path with inotify, so editors saving
+// through a rename are seen, and falls back to polling when inotify is not
+// available.
+func watchFile(ctx context.Context, path string, changed chan<- struct{}) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		pollFile(ctx, path, changed)
+		return nil
+	}
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	_, err = syscall.InotifyAddWatch(fd, dir, syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO|syscall.IN_CREATE)
+	if err != nil {
+		syscall.Close(fd)
+		pollFile(ctx, path, changed)
+		return nil
+	}
+
+	// a non blocking file uses the runtime poller, so Close unblocks Read
+	f := os.NewFile(uintptr(fd), "inotify")
+	go func() {
+		<-ctx.Done()
+		f.Close()
+	}()
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			for i := 0; i+syscall.SizeofInotifyEvent <= n; {
+				event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[i]))
+				start := i + syscall.SizeofInotifyEvent
+				i = start + int(event.Len)
+				if i > n {
+					break
+				}
+				if string(bytes.TrimRight(buf[start:i], "\x00")) == name {
+					notify(changed)
+				}
+			}
+		}
+	}()
+	return nil
+}
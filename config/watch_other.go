@@ -0,0 +1,11 @@
+//go:build !linux
+
+package config
+
+import "context"
+
+// watchFile polls path for changes, see pollFile.
+func watchFile(ctx context.Context, path string, changed chan<- struct{}) error {
+	pollFile(ctx, path, changed)
+	return nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// watchConfig starts watching a config file holding content and returns
+// its path and the channel receiving the changes.
+func watchConfig(t *testing.T, content string, lookupEnv func(string) (string, bool)) (string, chan [2]int) {
+	orgInterval, orgDelay, orgSubscribers := pollInterval, reloadDelay, subscribers
+	pollInterval, reloadDelay = 10*time.Millisecond, 10*time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		subscribersMu.Lock()
+		pollInterval, reloadDelay, subscribers = orgInterval, orgDelay, orgSubscribers
+		subscribersMu.Unlock()
+	})
+
+	dir := t.TempDir()
+	writeConfigFile(t, dir, content)
+	changes := make(chan [2]int, 10)
+	OnChange(func(old, new *Config) {
+		changes <- [2]int{old.ListenPort, new.ListenPort}
+	})
+	_, err := Watch(ctx, Options{SearchPaths: []string{dir}, LookupEnv: lookupEnv, Args: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, configFileName), changes
+}
+
+func receive(t *testing.T, changes chan [2]int) [2]int {
+	select {
+	case change := <-changes:
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("no config change")
+	}
+	return [2]int{}
+}
+
+func TestWatchHappyPath(t *testing.T) {
+	testData := []struct {
+		name   string
+		update func(path string) error
+	}{
+		{
+			name: "write",
+			update: func(path string) error {
+				return ioutil.WriteFile(path, []byte(`{"listenPort": 9090}`), 0644)
+			},
+		},
+		{
+			name: "rename",
+			update: func(path string) error {
+				tmp := path + ".tmp"
+				if err := ioutil.WriteFile(tmp, []byte(`{"listenPort": 9090}`), 0644); err != nil {
+					return err
+				}
+				return os.Rename(tmp, path)
+			},
+		},
+	}
+
+	for _, test := range testData {
+		path, changes := watchConfig(t, `{"listenPort": 8081}`, mockEnv(nil))
+		assert.Equal(t, 8081, Current().ListenPort, test.name)
+		assert.Nil(t, test.update(path), test.name)
+		assert.Equal(t, [2]int{8081, 9090}, receive(t, changes), test.name)
+		assert.Equal(t, 9090, Current().ListenPort, test.name)
+		assert.Equal(t, 8081, ConfigParams.ListenPort, test.name)
+	}
+}
+
+func TestWatchInvalidFile(t *testing.T) {
+	orgLogf := logf
+	defer func() {
+		logf = orgLogf
+	}()
+	errs := make(chan string, 10)
+	logf = func(format string, v ...interface{}) {
+		errs <- format
+	}
+
+	path, changes := watchConfig(t, `{"listenPort": 8081}`, mockEnv(nil))
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"listenPort": "http"}`), 0644))
+	select {
+	case <-errs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no error logged")
+	}
+	assert.Equal(t, 8081, Current().ListenPort)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"listenPort": 9090}`), 0644))
+	assert.Equal(t, [2]int{8081, 9090}, receive(t, changes))
+}
+
+func TestWatchSIGHUP(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no SIGHUP on windows")
+	}
+	env := make(chan map[string]string, 1)
+	env <- nil
+	lookupEnv := func(key string) (string, bool) {
+		m := <-env
+		env <- m
+		v, ok := m[key]
+		return v, ok
+	}
+	_, changes := watchConfig(t, `{"listenPort": 8081}`, lookupEnv)
+
+	<-env
+	env <- map[string]string{"APP_LISTEN_PORT": "9090"}
+	p, _ := os.FindProcess(os.Getpid())
+	assert.Nil(t, p.Signal(syscall.SIGHUP))
+	assert.Equal(t, [2]int{8081, 9090}, receive(t, changes))
+}
@@ -0,0 +1,143 @@
+package dto
+
+import "math/bits"
+
+// FieldSet implements ValidRequestDTO and IndexedRequestDTO when embedded in
+// a DTO struct, tracking the set fields in a bitset keyed by field index:
+//
+//	type PatchUserReq struct {
+//		dto.FieldSet
+//		Name string `dto:"name" required:"false"`
+//	}
+//
+// MarkAllUnset clears it without releasing memory, so DTOs can be reused
+// from a sync.Pool.
+type FieldSet struct {
+	names []string
+	set   bitset
+	zero  bitset
+}
+
+type bitset struct {
+	lo uint64
+	hi []uint64
+}
+
+func (b *bitset) get(i int) bool {
+	if i < 64 {
+		return b.lo&(1<<uint(i)) != 0
+	}
+	w := i/64 - 1
+	return w < len(b.hi) && b.hi[w]&(1<<uint(i%64)) != 0
+}
+
+func (b *bitset) put(i int, v bool) {
+	word := &b.lo
+	if i >= 64 {
+		w := i/64 - 1
+		for len(b.hi) <= w {
+			b.hi = append(b.hi, 0)
+		}
+		word = &b.hi[w]
+	}
+	if v {
+		*word |= 1 << uint(i%64)
+	} else {
+		*word &^= 1 << uint(i%64)
+	}
+}
+
+func (b *bitset) clear() {
+	b.lo = 0
+	for i := range b.hi {
+		b.hi[i] = 0
+	}
+}
+
+func (b *bitset) count() int {
+	n := bits.OnesCount64(b.lo)
+	for _, w := range b.hi {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+func (fs *FieldSet) index(dtoName string) int {
+	for i, name := range fs.names {
+		if name == dtoName {
+			return i
+		}
+	}
+	return -1
+}
+
+func (fs *FieldSet) AlreadySet(dtoName string) bool {
+	i := fs.index(dtoName)
+	return i >= 0 && fs.set.get(i)
+}
+
+// MarkSet marks dtoName set, binders implementing IndexedRequestDTO call
+// MarkSetIndex instead.
+func (fs *FieldSet) MarkSet(dtoName string) {
+	i := fs.index(dtoName)
+	if i < 0 {
+		// names may be shared with other values, never append in place
+		fs.names = append(fs.names[:len(fs.names):len(fs.names)], dtoName)
+		i = len(fs.names) - 1
+	}
+	fs.set.put(i, true)
+	fs.zero.put(i, false)
+}
+
+func (fs *FieldSet) MarkSetIndex(names []string, index int, zero bool) {
+	if len(fs.names) > 0 && len(names) > 0 && &fs.names[0] != &names[0] && fs.set.count() > 0 {
+		fs.rebase(names)
+	} else {
+		fs.names = names
+	}
+	fs.set.put(index, true)
+	fs.zero.put(index, zero)
+}
+
+// rebase moves the set fields onto names, which replace the names the bits
+// were set against. The set names missing from names are appended to a copy.
+func (fs *FieldSet) rebase(names []string) {
+	old, set, zero := fs.names, fs.set, fs.zero
+	fs.names, fs.set, fs.zero = names, bitset{}, bitset{}
+	for i, name := range old {
+		if !set.get(i) {
+			continue
+		}
+		j := fs.index(name)
+		if j < 0 {
+			fs.names = append(fs.names[:len(fs.names):len(fs.names)], name)
+			j = len(fs.names) - 1
+		}
+		fs.set.put(j, true)
+		fs.zero.put(j, zero.get(i))
+	}
+}
+
+func (fs *FieldSet) MarkAllUnset() {
+	fs.set.clear()
+	fs.zero.clear()
+}
+
+// SetFields returns the dto names of the set fields, in field order.
+func (fs *FieldSet) SetFields() []string {
+	names := make([]string, 0, fs.set.count())
+	for i, name := range fs.names {
+		if fs.set.get(i) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// IsZeroOrUnset reports whether dtoName was not set by the request or was
+// set to its zero value, such as a JSON null, which PATCH handlers usually
+// treat as clearing the field.
+func (fs *FieldSet) IsZeroOrUnset(dtoName string) bool {
+	i := fs.index(dtoName)
+	return i < 0 || !fs.set.get(i) || fs.zero.get(i)
+}
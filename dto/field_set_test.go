@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldSet(t *testing.T) {
+	names := []string{"name", "age", "hobby"}
+	fs := &FieldSet{}
+	var in IndexedRequestDTO = fs
+
+	in.MarkSetIndex(names, 2, false)
+	in.MarkSetIndex(names, 1, true)
+	assert.True(t, fs.AlreadySet("hobby"), "set by index")
+	assert.True(t, fs.AlreadySet("age"), "set to zero value")
+	assert.False(t, fs.AlreadySet("name"), "unset")
+	assert.False(t, fs.AlreadySet("unknown"), "unknown name")
+	assert.Equal(t, []string{"age", "hobby"}, fs.SetFields(), "set fields in field order")
+	assert.True(t, fs.IsZeroOrUnset("name"), "unset")
+	assert.True(t, fs.IsZeroOrUnset("age"), "set to zero value")
+	assert.False(t, fs.IsZeroOrUnset("hobby"), "set")
+
+	in.MarkSet("extra")
+	assert.True(t, fs.AlreadySet("extra"), "set by name")
+	assert.Equal(t, []string{"name", "age", "hobby"}, names, "shared names untouched")
+
+	in.MarkAllUnset()
+	assert.Equal(t, []string{}, fs.SetFields(), "all unset")
+	assert.True(t, fs.IsZeroOrUnset("age"), "all unset")
+}
+
+func TestFieldSetMarkSetBeforeIndex(t *testing.T) {
+	names := []string{"name", "age", "hobby"}
+	fs := &FieldSet{}
+	fs.MarkSet("extra")
+	fs.MarkSet("age")
+	fs.MarkSetIndex(names, 2, false)
+	assert.False(t, fs.AlreadySet("name"), "first field unset")
+	assert.True(t, fs.AlreadySet("age"), "set by name")
+	assert.True(t, fs.AlreadySet("extra"), "set by name")
+	assert.Equal(t, []string{"age", "hobby", "extra"}, fs.SetFields(), "set fields")
+	assert.Equal(t, []string{"name", "age", "hobby"}, names, "shared names untouched")
+
+	fs.MarkSetIndex(names, 0, true)
+	assert.Equal(t, []string{"name", "age", "hobby", "extra"}, fs.SetFields(), "set fields")
+	assert.True(t, fs.IsZeroOrUnset("name"), "zero value")
+}
+
+func TestFieldSetManyFields(t *testing.T) {
+	var names []string
+	for i := 0; i < 130; i++ {
+		names = append(names, fmt.Sprintf("f%d", i))
+	}
+	fs := &FieldSet{}
+	fs.MarkSetIndex(names, 3, false)
+	fs.MarkSetIndex(names, 64, false)
+	fs.MarkSetIndex(names, 129, true)
+	assert.Equal(t, []string{"f3", "f64", "f129"}, fs.SetFields(), "set fields")
+	assert.True(t, fs.IsZeroOrUnset("f129"), "zero value")
+	assert.False(t, fs.IsZeroOrUnset("f64"), "set")
+
+	fs.MarkAllUnset()
+	assert.False(t, fs.AlreadySet("f64"), "all unset")
+}
@@ -5,3 +5,12 @@ type ValidRequestDTO interface {
 	MarkSet(dtoName string)
 	MarkAllUnset()
 }
+
+// IndexedRequestDTO is implemented by DTOs tracking their set fields by
+// position, like FieldSet. names holds the dto names of the DTO type in
+// binding order and is shared by all the values of the type, zero reports
+// whether the bound value is the zero value of the field.
+type IndexedRequestDTO interface {
+	ValidRequestDTO
+	MarkSetIndex(names []string, index int, zero bool)
+}
@@ -0,0 +1,399 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/yikailee/golang/dto"
+)
+
+// bindPlans caches the *bindPlan of every bound DTO struct type.
+var bindPlans sync.Map
+
+// ErrTooDeep is returned when the params of a recursive DTO nest deeper than
+// maxRecursiveDepth, every level compiles a plan which is kept.
+var ErrTooDeep = errors.New("params nested too deep")
+
+const maxRecursiveDepth = 32
+
+// bindPlan is the binding of a struct type compiled from its tags, so that
+// binding a request only runs the precomputed setters of its fields.
+type bindPlan struct {
+	fields []*fieldPlan
+	names  *planNames
+	// bodyFields maps the dto names that may appear in a JSON body to their
+	// field, nil for the fields of embedded struct pointers
+	bodyFields map[string]*fieldPlan
+	// requiresBody is set when a required field without default binds only
+	// from the body
+	requiresBody bool
+	// strict is set by a blank field tagged strict:"true"
+	strict bool
+	// known holds the keys of the plan fields by source, for strict binding
+	known knownParams
+	// depth counts the recursive fields enclosing the plan
+	depth int
+}
+
+// planNames lists the keys of a root plan and of its nested plans, the
+// position of a key is the index passed to dto.IndexedRequestDTO. The plans
+// of recursive fields add their keys when they are first bound.
+type planNames struct {
+	sync.Mutex
+	keys []string
+}
+
+func (names *planNames) add(key string) int {
+	names.Lock()
+	defer names.Unlock()
+	names.keys = append(names.keys, key)
+	return len(names.keys) - 1
+}
+
+func (names *planNames) list() []string {
+	names.Lock()
+	defer names.Unlock()
+	return names.keys
+}
+
+type fieldPlan struct {
+	index    []int  // index path through embedded structs
+	bit      int    // position of key in the plan names
+	name     string // dto name
+	key      string // dto name prefixed by the enclosing dto names
+	source   string
+	required bool
+	typ      reflect.Type
+
+	set        valuesSetter
+	defaults   []string
+	hasDefault bool
+	files      *fileRules
+	collection string
+	maps       *mapRules
+
+	// nested is the plan of a named nested struct, or of an embedded struct
+	// pointer when name is empty
+	nested *bindPlan
+	ptr    bool
+	// recursive is set instead of nested for a struct of a type enclosing
+	// the field, its plan is compiled when the field is first bound
+	recursive *recursivePlan
+}
+
+type recursivePlan struct {
+	once sync.Once
+	plan *bindPlan
+	err  error
+}
+
+// nestedPlan returns the plan of a nested struct field of plan, compiling it
+// for recursive fields.
+func (fp *fieldPlan) nestedPlan(plan *bindPlan) (*bindPlan, error) {
+	if fp.recursive == nil {
+		return fp.nested, nil
+	}
+	r := fp.recursive
+	r.once.Do(func() {
+		if plan.depth >= maxRecursiveDepth {
+			r.err = fmt.Errorf("%w: %s", ErrTooDeep, fp.key)
+			return
+		}
+		ft := fp.typ
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		r.plan, r.err = compileBindPlan(ft, fp.key+".", plan.names, []reflect.Type{ft}, plan.depth+1)
+	})
+	return r.plan, r.err
+}
+
+func getBindPlan(t reflect.Type) (*bindPlan, error) {
+	if plan, ok := bindPlans.Load(t); ok {
+		return plan.(*bindPlan), nil
+	}
+	plan, err := compileBindPlan(t, "", &planNames{}, []reflect.Type{t}, 0)
+	if err != nil {
+		return nil, err
+	}
+	plan.known = newPlanKnownParams(plan)
+	actual, _ := bindPlans.LoadOrStore(t, plan)
+	return actual.(*bindPlan), nil
+}
+
+// compileBindPlan compiles the plan of t, nested in the struct types of
+// enclosing, which end with t, and in depth recursive fields.
+func compileBindPlan(t reflect.Type, prefix string, names *planNames, enclosing []reflect.Type, depth int) (*bindPlan, error) {
+	plan := &bindPlan{bodyFields: make(map[string]*fieldPlan), names: names, depth: depth}
+	if err := plan.addFields(t, nil, prefix, enclosing); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+func (plan *bindPlan) addFields(t reflect.Type, index []int, prefix string, enclosing []reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Name == "_" {
+			plan.strict = plan.strict || f.Tag.Get("strict") == "true"
+			continue
+		}
+		wanted, source := parseDTOTag(f.Tag.Get("dto"))
+		fieldIndex := append(append([]int(nil), index...), i)
+		if nestedStructType(f.Type) && (wanted != "" || f.Anonymous) {
+			if wanted == "" && f.Type.Kind() == reflect.Struct {
+				// promote the fields of embedded structs
+				if err := plan.addFields(f.Type, fieldIndex, prefix, enclosing); err != nil {
+					return err
+				}
+				continue
+			}
+			ft, subPrefix := f.Type, prefix
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if wanted != "" {
+				subPrefix = prefix + wanted + "."
+			}
+			fp := &fieldPlan{
+				index:    fieldIndex,
+				name:     wanted,
+				key:      prefix + wanted,
+				required: !(f.Tag.Get("required") == "false"),
+				typ:      f.Type,
+				ptr:      f.Type.Kind() == reflect.Ptr,
+			}
+			if containsType(enclosing, ft) {
+				if wanted == "" {
+					return fmt.Errorf("%w: recursive embedded %s", ErrUnhandleType, ft)
+				}
+				fp.recursive = &recursivePlan{}
+				plan.addField(fp)
+				plan.bodyFields[wanted] = fp
+				continue
+			}
+			nested, err := compileBindPlan(ft, subPrefix, plan.names, append(enclosing[:len(enclosing):len(enclosing)], ft), plan.depth)
+			if err != nil {
+				return err
+			}
+			fp.nested = nested
+			plan.addField(fp)
+			plan.requiresBody = plan.requiresBody || fp.required && nested.requiresBody
+			if wanted != "" {
+				plan.bodyFields[wanted] = fp
+			} else {
+				for name, nfp := range nested.bodyFields {
+					plan.bodyFields[name] = nfp
+				}
+			}
+			continue
+		}
+		if wanted == "" {
+			continue
+		}
+
+		fp := &fieldPlan{
+			index:    fieldIndex,
+			name:     wanted,
+			key:      prefix + wanted,
+			source:   source,
+			required: !(f.Tag.Get("required") == "false"),
+			typ:      f.Type,
+			set:      newValuesSetter(f.Type),
+		}
+		if source != "" && !knownSource(source) {
+			return fmt.Errorf("%s: %w: %s", fp.key, ErrUnknownSource, source)
+		}
+		fp.collection = f.Tag.Get("collection")
+		if err := checkCollection(f.Type, fp.collection); err != nil {
+			return fmt.Errorf("%s: %w", fp.key, err)
+		}
+		if f.Type.Kind() == reflect.Map {
+			rules, err := parseMapRules(f.Type, f.Tag)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fp.key, err)
+			}
+			switch source {
+			case "", SourcePath, SourceQuery, SourceForm, SourceBody:
+			default:
+				return fmt.Errorf("%s: %w: %s for a map", fp.key, ErrUnknownSource, source)
+			}
+			fp.maps = rules
+		}
+		if isFileType(f.Type) {
+			rules, err := parseFileRules(f.Tag)
+			if err != nil {
+				return fmt.Errorf("%s: %w", fp.key, err)
+			}
+			fp.files = &rules
+			if source == "" {
+				fp.source = SourceFile
+			}
+		}
+		if def, ok := f.Tag.Lookup("default"); ok {
+			fp.defaults, fp.hasDefault = splitDefault(f.Type, def), true
+			if err := fp.set(reflect.New(f.Type).Elem(), fp.defaults); err != nil {
+				return fmt.Errorf("%s: bad default %q: %w", fp.key, def, err)
+			}
+		}
+		plan.addField(fp)
+		if fp.required && !fp.hasDefault && fp.source == SourceBody {
+			plan.requiresBody = true
+		}
+		if fp.source == "" || fp.source == SourceBody {
+			plan.bodyFields[wanted] = fp
+		}
+	}
+	return nil
+}
+
+func (plan *bindPlan) addField(fp *fieldPlan) {
+	if fp.name != "" {
+		fp.bit = plan.names.add(fp.key)
+	}
+	plan.fields = append(plan.fields, fp)
+}
+
+// markSet marks the field of fp set in in, by index when in tracks its
+// fields by position.
+func (plan *bindPlan) markSet(in dto.ValidRequestDTO, fp *fieldPlan, fv reflect.Value) {
+	if idx, ok := in.(dto.IndexedRequestDTO); ok {
+		idx.MarkSetIndex(plan.names.list(), fp.bit, fv.IsZero())
+		return
+	}
+	in.MarkSet(fp.key)
+}
+
+// bind binds the fields of v following the plan. Named nested structs read
+// the matching nested JSON object of body. It reports whether any field was
+// set.
+func (plan *bindPlan) bind(v reflect.Value, src *paramSources, body map[string]interface{}, in dto.ValidRequestDTO) (set bool, err error) {
+	for _, fp := range plan.fields {
+		fv := v.FieldByIndex(fp.index)
+		if fp.nested != nil || fp.recursive != nil {
+			subBody := body
+			if fp.name != "" {
+				subBody, _ = body[fp.name].(map[string]interface{})
+				if subBody == nil && !src.hasValuesWithPrefix(fp.key+".") {
+					if !fp.required {
+						continue
+					}
+					if fp.recursive != nil {
+						// do not descend into the params of a recursive field
+						// that has none
+						return set, fmt.Errorf("%w: %s", ErrMissingParam, fp.key)
+					}
+				}
+			}
+			nested, err := fp.nestedPlan(plan)
+			if err != nil {
+				return set, err
+			}
+			subSet, err := fp.bindNested(nested, fv, src, subBody, in)
+			if err != nil {
+				return set, err
+			}
+			if subSet && fp.name != "" {
+				plan.markSet(in, fp, fv)
+			}
+			set = set || subSet
+			continue
+		}
+
+		var (
+			value interface{}
+			ok    bool
+		)
+		if fp.maps != nil {
+			value, ok, err = src.lookupMap(fp.key, fp.name, fp.source, fp.maps.style, body)
+		} else {
+			value, ok, err = src.lookupCollection(fp.key, fp.name, fp.source, fp.collection, body)
+		}
+		if err != nil {
+			return set, err
+		}
+		if !ok {
+			if fp.hasDefault {
+				// defaults are not marked set
+				if err := fp.set(fv, fp.defaults); err != nil {
+					return set, fmt.Errorf("%s: %w", fp.key, err)
+				}
+				continue
+			}
+			if fp.required {
+				return set, fmt.Errorf("%w: %s", ErrMissingParam, fp.key)
+			}
+			continue
+		}
+		switch value := value.(type) {
+		case []string:
+			err = fp.set(fv, value)
+		case contextValue:
+			err = setContextValue(fv, value)
+		case []*multipart.FileHeader:
+			err = setFileValue(fv, value, fp.files)
+		case mapValues:
+			err = setMapValue(fv, value, fp.maps)
+		default:
+			if fp.maps != nil {
+				err = checkMapKeys(value, fp.maps)
+			}
+			if err == nil {
+				err = setJSONValue(fv, value)
+			}
+		}
+		if err != nil {
+			if fp.required {
+				return set, fmt.Errorf("%s: %w", fp.key, err)
+			}
+			continue
+		}
+		plan.markSet(in, fp, fv)
+		set = true
+	}
+
+	return set, nil
+}
+
+func (fp *fieldPlan) bindNested(nested *bindPlan, rVal reflect.Value, src *paramSources, body map[string]interface{}, in dto.ValidRequestDTO) (set bool, err error) {
+	if !fp.ptr {
+		return nested.bind(rVal, src, body, in)
+	}
+	if !rVal.IsNil() {
+		return nested.bind(rVal.Elem(), src, body, in)
+	}
+	if !rVal.CanSet() {
+		return false, nil
+	}
+
+	// only allocate the nested struct when one of its fields gets set
+	v := reflect.New(fp.typ.Elem())
+	set, err = nested.bind(v.Elem(), src, body, in)
+	if set {
+		rVal.Set(v)
+	}
+	return
+}
+
+func containsType(types []reflect.Type, t reflect.Type) bool {
+	for _, e := range types {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// splitDefault splits the default tag of slices and arrays, which take a
+// comma separated list ("a,b,c").
+func splitDefault(t reflect.Type, def string) []string {
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice:
+		return strings.Split(def, ",")
+	}
+	return []string{def}
+}
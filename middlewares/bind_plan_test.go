@@ -0,0 +1,204 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yikailee/golang/dto"
+)
+
+type patchUser struct {
+	dto.FieldSet
+	Name     string  `dto:"name" required:"false"`
+	Nickname *string `dto:"nickname" required:"false"`
+	Filter   struct {
+		City string `dto:"city" required:"false"`
+	} `dto:"filter" required:"false"`
+}
+
+func TestBindFieldSet(t *testing.T) {
+	pool := sync.Pool{New: func() interface{} { return &patchUser{} }}
+	testData := []struct {
+		name       string
+		jsonBody   string
+		wantedSet  []string
+		wantedZero []string
+	}{
+		{
+			name:       "set and cleared fields",
+			jsonBody:   `{"name": "bob", "nickname": null}`,
+			wantedSet:  []string{"name", "nickname"},
+			wantedZero: []string{"nickname", "filter", "filter.city"},
+		},
+		{
+			name:       "reused from pool",
+			jsonBody:   `{"filter": {"city": "paris"}}`,
+			wantedSet:  []string{"filter.city", "filter"},
+			wantedZero: []string{"name", "nickname"},
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("PATCH", "http://localhost:8080/users/1", strings.NewReader(test.jsonBody))
+		r.Header.Add("Content-Type", "application/json")
+		u := pool.Get().(*patchUser)
+		err := BindRequestParams(r, u)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wantedSet, u.SetFields(), test.name)
+		for _, name := range test.wantedZero {
+			assert.True(t, u.IsZeroOrUnset(name), test.name+" "+name)
+		}
+		u.MarkAllUnset()
+		*u = patchUser{FieldSet: u.FieldSet}
+		pool.Put(u)
+	}
+}
+
+type treeNode struct {
+	dto.FieldSet
+	Name     string     `dto:"name"`
+	Child    *treeNode  `dto:"child" required:"false"`
+	Children []treeNode `dto:"children" required:"false"`
+	Other    *otherNode `dto:"other" required:"false"`
+}
+
+type otherNode struct {
+	Tree *treeNode `dto:"tree" required:"false"`
+}
+
+type requiredTreeNode struct {
+	Name  string            `dto:"name"`
+	Child *requiredTreeNode `dto:"child"`
+}
+
+func (*requiredTreeNode) AlreadySet(string) bool { return false }
+func (*requiredTreeNode) MarkSet(string)         {}
+func (*requiredTreeNode) MarkAllUnset()          {}
+
+func TestBindRecursiveDTOHappyPath(t *testing.T) {
+	assert.Nil(t, Register(&treeNode{}), "register")
+
+	testData := []struct {
+		name      string
+		url       string
+		jsonBody  string
+		opts      []Option
+		wanted    func(n *treeNode) bool
+		wantedSet []string
+	}{
+		{
+			name:      "no child",
+			url:       "http://localhost:8080/trees?name=root",
+			wanted:    func(n *treeNode) bool { return n.Name == "root" && n.Child == nil },
+			wantedSet: []string{"name"},
+		},
+		{
+			name: "query params",
+			url:  "http://localhost:8080/trees?name=root&child.name=a&child.child.name=b",
+			opts: []Option{Strict()},
+			wanted: func(n *treeNode) bool {
+				return n.Child.Name == "a" && n.Child.Child.Name == "b" && n.Child.Child.Child == nil
+			},
+			wantedSet: []string{"name", "child", "child.name", "child.child", "child.child.name"},
+		},
+		{
+			name:     "json body",
+			url:      "http://localhost:8080/trees",
+			jsonBody: `{"name": "root", "child": {"name": "a", "children": [{"name": "c", "child": {"name": "d"}}]}, "other": {"tree": {"name": "e"}}}`,
+			opts:     []Option{DisallowUnknownFields()},
+			wanted: func(n *treeNode) bool {
+				return n.Child.Name == "a" && n.Child.Children[0].Child.Name == "d" && n.Other.Tree.Name == "e"
+			},
+			wantedSet: []string{"name", "child", "child.name", "child.children", "other", "other.tree", "other.tree.name"},
+		},
+	}
+
+	for _, test := range testData {
+		method, body := "GET", ""
+		if test.jsonBody != "" {
+			method, body = "POST", test.jsonBody
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(body))
+		r.Header.Add("Content-Type", "application/json")
+		n := &treeNode{}
+		err := BindRequestParams(r, n, test.opts...)
+		assert.Nil(t, err, test.name)
+		if err == nil {
+			assert.True(t, test.wanted(n), test.name)
+			assert.ElementsMatch(t, test.wantedSet, n.SetFields(), test.name)
+		}
+	}
+}
+
+func TestBindRecursiveDTODepth(t *testing.T) {
+	plan, err := getBindPlan(reflect.TypeOf(treeNode{}))
+	if !assert.Nil(t, err, "plan") {
+		return
+	}
+	deepURL := func(depth int) string {
+		url := "http://localhost:8080/trees?name=root"
+		for i := 1; i <= depth; i++ {
+			url += "&" + strings.Repeat("child.", i) + "name=x"
+		}
+		return url
+	}
+
+	r, _ := http.NewRequest("GET", deepURL(maxRecursiveDepth), nil)
+	assert.Nil(t, BindRequestParams(r, &treeNode{}), "max depth")
+	names := len(plan.names.list())
+
+	for _, depth := range []int{maxRecursiveDepth + 1, 4 * maxRecursiveDepth} {
+		r, _ := http.NewRequest("GET", deepURL(depth), nil)
+		err := BindRequestParams(r, &treeNode{})
+		assert.True(t, errors.Is(err, ErrTooDeep), "too deep")
+		assert.Equal(t, names, len(plan.names.list()), "names bounded")
+	}
+}
+
+func TestBindRecursiveDTOError(t *testing.T) {
+	testData := []struct {
+		name     string
+		url      string
+		jsonBody string
+		opts     []Option
+		in       dto.ValidRequestDTO
+		wanted   error
+	}{
+		{
+			name:   "missing nested name",
+			url:    "http://localhost:8080/trees?name=root&child.child.nick=b",
+			in:     &treeNode{},
+			wanted: ErrMissingParam,
+		},
+		{
+			name:     "unknown nested field",
+			url:      "http://localhost:8080/trees",
+			jsonBody: `{"name": "root", "child": {"name": "a", "child": {"nmae": "b"}}}`,
+			opts:     []Option{DisallowUnknownFields()},
+			in:       &treeNode{},
+			wanted:   ErrUnknownField,
+		},
+		{
+			name:   "required recursive field",
+			url:    "http://localhost:8080/trees?name=root&child.name=a",
+			in:     &requiredTreeNode{},
+			wanted: ErrMissingParam,
+		},
+	}
+
+	for _, test := range testData {
+		method, body := "GET", ""
+		if test.jsonBody != "" {
+			method, body = "POST", test.jsonBody
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(body))
+		r.Header.Add("Content-Type", "application/json")
+		err := BindRequestParams(r, test.in, test.opts...)
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+	}
+}
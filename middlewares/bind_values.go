@@ -0,0 +1,230 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// BindValues gives the Bind methods generated by cmd/dtogen access to the
+// params of a request, with the same sources and precedence as
+// BindRequestParams.
+type BindValues struct {
+	src         *paramSources
+	body        map[string]interface{}
+	unsupported bool
+	strict      bool
+}
+
+// NewBindValues parses r. bodyFields lists the dto names that may appear in
+// a JSON body, for the DisallowUnknownFields option.
+func NewBindValues(r *http.Request, bodyFields []string, opts ...Option) (*BindValues, error) {
+	o := newParseOptions(opts)
+	body, supported, err := parseRequestBody(r, o)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil && o.disallowUnknownFields {
+		known := make(map[string]bool, len(bodyFields))
+		for _, name := range bodyFields {
+			known[name] = true
+		}
+		for k := range body {
+			if !known[k] {
+				return nil, fmt.Errorf("%w: %s", ErrUnknownField, k)
+			}
+		}
+	}
+	return &BindValues{src: newParamSources(r, o), body: body, unsupported: !supported, strict: o.strict}, nil
+}
+
+// Strict enables strict binding for DTOs with a strict marker field.
+func (bv *BindValues) Strict() {
+	bv.strict = true
+}
+
+// CheckUnknownParams fails with ErrUnknownParam when binding is strict and
+// the query, form or body has keys not in params, the dto tags of the DTO
+// fields ("id,path").
+func (bv *BindValues) CheckUnknownParams(params ...string) error {
+	if !bv.strict {
+		return nil
+	}
+	known := make(knownParams)
+	for _, p := range params {
+		known.add(parseDTOTag(p))
+	}
+	unknown := unknownParams(bv.src, known)
+	for k := range bv.body {
+		if !known.has(k, SourceBody) {
+			unknown = append(unknown, k+" (body)")
+		}
+	}
+	return unknownParamError(unknown)
+}
+
+// RequireBody returns ErrUnsupportedMediaType when the request has a body
+// no BodyDecoder handles, for DTOs with required body fields.
+func (bv *BindValues) RequireBody() error {
+	if bv.unsupported {
+		return ErrUnsupportedMediaType
+	}
+	return nil
+}
+
+// Lookup finds the value of the dto name in source, or in the first source
+// that has it when source is empty.
+func (bv *BindValues) Lookup(name, source string) (BindValue, bool, error) {
+	return bv.LookupCollection(name, source, "")
+}
+
+// LookupCollection is Lookup for slice and array fields sent in the given
+// collection format.
+func (bv *BindValues) LookupCollection(name, source, format string) (BindValue, bool, error) {
+	value, ok, err := bv.src.lookupCollection(name, name, source, format, bv.body)
+	if err != nil || !ok {
+		return BindValue{}, false, err
+	}
+	switch value := value.(type) {
+	case []string:
+		return BindValue{strs: value}, true, nil
+	case contextValue:
+		return BindValue{}, false, fmt.Errorf("%s: %w", name, ErrUnhandleType)
+	}
+	return BindValue{jv: value, isJSON: true}, true, nil
+}
+
+// MissingParam returns the error of a missing required param.
+func MissingParam(name string) error {
+	return fmt.Errorf("%w: %s", ErrMissingParam, name)
+}
+
+// FieldError returns the error of a required param that could not be bound.
+func FieldError(name string, err error) error {
+	return fmt.Errorf("%s: %w", name, err)
+}
+
+// BindValue is a param value, either the strings of the url, form, header
+// and cookie sources or a value decoded from the JSON body. Its conversions
+// match the ones of BindRequestParams.
+type BindValue struct {
+	strs   []string
+	jv     interface{}
+	isJSON bool
+}
+
+// StringValues returns a BindValue holding strs, used for default values.
+func StringValues(strs ...string) BindValue {
+	return BindValue{strs: strs}
+}
+
+// IsNull reports whether the value is a JSON null.
+func (v BindValue) IsNull() bool {
+	return v.isJSON && v.jv == nil
+}
+
+func (v BindValue) first() (string, error) {
+	if len(v.strs) == 0 {
+		return "", ErrEmptyValue
+	}
+	return v.strs[0], nil
+}
+
+func (v BindValue) String() (string, error) {
+	if !v.isJSON {
+		return v.first()
+	}
+	switch jv := v.jv.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return jv, nil
+	case json.Number:
+		return jv.String(), nil
+	case bool:
+		return strconv.FormatBool(jv), nil
+	}
+	return "", ErrErrorType
+}
+
+func (v BindValue) Bool() (bool, error) {
+	if v.isJSON {
+		switch jv := v.jv.(type) {
+		case nil:
+			return false, nil
+		case bool:
+			return jv, nil
+		case string:
+			return strconv.ParseBool(jv)
+		}
+		return false, ErrErrorType
+	}
+	str, err := v.first()
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(str)
+}
+
+func (v BindValue) number() (string, error) {
+	if !v.isJSON {
+		return v.first()
+	}
+	switch jv := v.jv.(type) {
+	case string:
+		return jv, nil
+	case json.Number:
+		return jv.String(), nil
+	}
+	return "", ErrErrorType
+}
+
+// Int converts the value to an integer of bitSize bits, 0 for int.
+func (v BindValue) Int(bitSize int) (int64, error) {
+	if v.IsNull() {
+		return 0, nil
+	}
+	str, err := v.number()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(str, 10, bitSize)
+}
+
+// Float converts the value to a float of bitSize bits.
+func (v BindValue) Float(bitSize int) (float64, error) {
+	if v.IsNull() {
+		return 0, nil
+	}
+	str, err := v.number()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(str, bitSize)
+}
+
+// Len returns the number of elements bound into a slice or an array.
+func (v BindValue) Len() (int, error) {
+	if !v.isJSON {
+		if len(v.strs) == 0 {
+			return 0, ErrEmptyValue
+		}
+		return len(v.strs), nil
+	}
+	switch jv := v.jv.(type) {
+	case nil:
+		return 0, nil
+	case []interface{}:
+		return len(jv), nil
+	}
+	return 0, ErrErrorType
+}
+
+// Index returns the i-th element of a value checked with Len.
+func (v BindValue) Index(i int) BindValue {
+	if !v.isJSON {
+		return BindValue{strs: v.strs[i : i+1]}
+	}
+	return BindValue{jv: v.jv.([]interface{})[i], isJSON: true}
+}
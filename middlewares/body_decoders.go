@@ -0,0 +1,268 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrIllegalBody          = errors.New("ilegal request body")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+)
+
+// BodyDecoder decodes a request body into an object whose values are bound
+// like the ones of a JSON body. Numbers may be of any Go numeric type and
+// maps may have non string keys, they are normalized before binding. An
+// empty body decodes to nil.
+type BodyDecoder func(r io.Reader) (map[string]interface{}, error)
+
+var bodyDecoders = struct {
+	sync.RWMutex
+	m map[string]BodyDecoder
+}{m: make(map[string]BodyDecoder)}
+
+// RegisterBodyDecoder registers dec for bodies of mediaType, replacing the
+// decoder registered before, if any.
+func RegisterBodyDecoder(mediaType string, dec BodyDecoder) {
+	bodyDecoders.Lock()
+	defer bodyDecoders.Unlock()
+	bodyDecoders.m[strings.ToLower(mediaType)] = dec
+}
+
+func lookupBodyDecoder(mediaType string) (BodyDecoder, bool) {
+	bodyDecoders.RLock()
+	defer bodyDecoders.RUnlock()
+	dec, ok := bodyDecoders.m[mediaType]
+	return dec, ok
+}
+
+// formBody marks the media types whose values r.ParseMultipartForm already
+// parsed into r.PostForm.
+func formBody(r io.Reader) (map[string]interface{}, error) {
+	return nil, nil
+}
+
+func init() {
+	RegisterBodyDecoder("application/json", decodeJSONObject)
+	RegisterBodyDecoder("application/x-www-form-urlencoded", formBody)
+	RegisterBodyDecoder("multipart/form-data", formBody)
+	RegisterBodyDecoder("application/xml", decodeXMLObject)
+	RegisterBodyDecoder("text/xml", decodeXMLObject)
+	for _, mt := range []string{"application/yaml", "application/x-yaml", "text/yaml"} {
+		RegisterBodyDecoder(mt, decodeYAMLObject)
+	}
+	for _, mt := range []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"} {
+		RegisterBodyDecoder(mt, decodeMsgpackObject)
+	}
+	RegisterBodyDecoder("application/cbor", decodeCBORObject)
+}
+
+// parseBody decodes the body of POST, PUT and PATCH requests with the
+// decoder registered for their media type. supported is false when a body
+// was sent without a matching decoder.
+func parseBody(r *http.Request, o *parseOptions) (body map[string]interface{}, supported bool, err error) {
+	if r.Method != "POST" && r.Method != "PUT" && r.Method != "PATCH" {
+		return nil, true, nil
+	}
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true, nil
+	}
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		ct = "application/octet-stream"
+	}
+	ct, _, err = mime.ParseMediaType(ct)
+	if err != nil {
+		return nil, false, nil
+	}
+	dec, ok := lookupBodyDecoder(ct)
+	if !ok {
+		return nil, false, nil
+	}
+
+	body, err = dec(r.Body)
+	if err != nil {
+		if isBodyTooLarge(err) {
+			return nil, true, ErrBodyTooLarge
+		}
+		if errors.Is(err, ErrBodyTooLarge) || errors.Is(err, ErrIllegalJSON) {
+			return nil, true, err
+		}
+		return nil, true, fmt.Errorf("%w: %v", ErrIllegalBody, err)
+	}
+	if ct == "application/json" || body == nil {
+		return body, true, nil
+	}
+	normalized, err := normalizeBodyValue(body)
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %v", ErrIllegalBody, err)
+	}
+	return normalized.(map[string]interface{}), true, nil
+}
+
+// normalizeBodyValue converts a decoded value to the types of a JSON body
+// decoded with json.Number.
+func normalizeBodyValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case nil, string, bool, json.Number:
+		return v, nil
+	case map[string]interface{}:
+		for k, e := range v {
+			n, err := normalizeBodyValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[k] = n
+		}
+		return v, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			n, err := normalizeBodyValue(e)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = n
+		}
+		return m, nil
+	case []interface{}:
+		for i, e := range v {
+			n, err := normalizeBodyValue(e)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = n
+		}
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case float32:
+		return floatNumber(float64(v), 32)
+	case float64:
+		return floatNumber(v, 64)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return json.Number(strconv.FormatUint(rv.Uint(), 10)), nil
+	}
+	return nil, fmt.Errorf("unsupported value type %T", v)
+}
+
+func floatNumber(f float64, bitSize int) (interface{}, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, fmt.Errorf("unsupported number %v", f)
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, bitSize)), nil
+}
+
+// decodeXMLObject decodes the children of the root element into an object:
+// elements with children become nested objects, repeated elements arrays
+// and attributes string values.
+func decodeXMLObject(r io.Reader) (map[string]interface{}, error) {
+	d := xml.NewDecoder(r)
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := t.(xml.StartElement); ok {
+			v, err := decodeXMLElement(d, start)
+			if err != nil {
+				return nil, err
+			}
+			if obj, ok := v.(map[string]interface{}); ok {
+				return obj, nil
+			}
+			return map[string]interface{}{}, nil
+		}
+	}
+}
+
+func decodeXMLElement(d *xml.Decoder, start xml.StartElement) (interface{}, error) {
+	obj := make(map[string]interface{})
+	for _, attr := range start.Attr {
+		obj[attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	for {
+		t, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch prev := obj[name].(type) {
+			case nil:
+				obj[name] = v
+			case []interface{}:
+				obj[name] = append(prev, v)
+			default:
+				obj[name] = []interface{}{prev, v}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(obj) == 0 {
+				return strings.TrimSpace(text.String()), nil
+			}
+			return obj, nil
+		}
+	}
+}
+
+func decodeYAMLObject(r io.Reader) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	if err := yaml.NewDecoder(r).Decode(&obj); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return obj, nil
+}
+
+func decodeMsgpackObject(r io.Reader) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	if err := msgpack.NewDecoder(r).Decode(&obj); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return obj, nil
+}
+
+var cborDecMode, _ = cbor.DecOptions{
+	DefaultMapType: reflect.TypeOf(map[string]interface{}(nil)),
+}.DecMode()
+
+func decodeCBORObject(r io.Reader) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	if err := cborDecMode.NewDecoder(r).Decode(&obj); err != nil && err != io.EOF {
+		return nil, err
+	}
+	return obj, nil
+}
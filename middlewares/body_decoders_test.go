@@ -0,0 +1,200 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
+	"github.com/yikailee/golang/dto"
+)
+
+var typedBody = map[string]interface{}{
+	"id":     int64(9007199254740993),
+	"score":  3.5,
+	"active": true,
+	"items": []interface{}{
+		map[string]interface{}{"name": "a", "count": 2},
+		map[string]interface{}{"name": "b"},
+	},
+	"owner": map[string]interface{}{"name": "c"},
+}
+
+func mustMarshal(t *testing.T, marshal func(interface{}) ([]byte, error), v interface{}) string {
+	b, err := marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestBindBodyFormatsHappyPath(t *testing.T) {
+	testData := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "xml",
+			contentType: "application/xml",
+			body: `<user><id>9007199254740993</id><score>3.5</score><active>true</active>` +
+				`<items><name>a</name><count>2</count></items><items name="b"/><owner name="c"/></user>`,
+		},
+		{
+			name:        "text xml with charset",
+			contentType: "text/xml; charset=utf-8",
+			body: `<?xml version="1.0"?><user id="9007199254740993" score="3.5" active="true">` +
+				`<items name="a" count="2"/><items><name>b</name></items><owner><name>c</name></owner></user>`,
+		},
+		{
+			name:        "yaml",
+			contentType: "application/yaml",
+			body:        "id: 9007199254740993\nscore: 3.5\nactive: true\nitems:\n  - {name: a, count: 2}\n  - name: b\nowner:\n  name: c\n",
+		},
+		{
+			name:        "msgpack",
+			contentType: "application/msgpack",
+			body:        mustMarshal(t, msgpack.Marshal, typedBody),
+		},
+		{
+			name:        "cbor",
+			contentType: "application/cbor",
+			body:        mustMarshal(t, cbor.Marshal, typedBody),
+		},
+	}
+
+	wanted := &jsonUser{
+		ID:       9007199254740993,
+		Score:    3.5,
+		Active:   true,
+		Items:    []jsonItem{{Name: "a", Count: 2}, {Name: "b"}},
+		Owner:    &jsonItem{Name: "c"},
+		setItems: map[string]bool{"id": true, "score": true, "active": true, "items": true, "owner": true, "owner.name": true},
+	}
+	for _, test := range testData {
+		r, _ := http.NewRequest("POST", "http://localhost:8080/users", bytes.NewBufferString(test.body))
+		r.Header.Add("Content-Type", test.contentType)
+		u := &jsonUser{}
+		err := BindRequestParams(r, u)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, wanted, u, test.name)
+	}
+}
+
+func TestBindBodyFormatsError(t *testing.T) {
+	testData := []struct {
+		name        string
+		contentType string
+		body        string
+		wanted      error
+	}{
+		{
+			name:        "illegal xml",
+			contentType: "application/xml",
+			body:        `<user><id>1</id>`,
+			wanted:      ErrIllegalBody,
+		},
+		{
+			name:        "illegal yaml",
+			contentType: "application/x-yaml",
+			body:        "id: [1",
+			wanted:      ErrIllegalBody,
+		},
+		{
+			name:        "yaml list",
+			contentType: "text/yaml",
+			body:        "- 1\n- 2\n",
+			wanted:      ErrIllegalBody,
+		},
+		{
+			name:        "illegal cbor",
+			contentType: "application/cbor",
+			body:        "\xff\xff",
+			wanted:      ErrIllegalBody,
+		},
+		{
+			name:        "boolean into int",
+			contentType: "application/msgpack",
+			body:        mustMarshal(t, msgpack.Marshal, map[string]interface{}{"id": true}),
+			wanted:      ErrErrorType,
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("POST", "http://localhost:8080/users", bytes.NewBufferString(test.body))
+		r.Header.Add("Content-Type", test.contentType)
+		err := BindRequestParams(r, &jsonUser{})
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+	}
+}
+
+type bodyOnlyUser struct {
+	dto.FieldSet
+	ID   int64  `dto:"id,path" required:"false"`
+	Name string `dto:"name,body"`
+}
+
+func TestUnsupportedMediaType(t *testing.T) {
+	testData := []struct {
+		name        string
+		contentType string
+		in          dto.ValidRequestDTO
+		wanted      error
+	}{
+		{
+			name:        "required body field",
+			contentType: "text/plain",
+			in:          &bodyOnlyUser{},
+			wanted:      ErrUnsupportedMediaType,
+		},
+		{
+			name:        "no content type",
+			contentType: "",
+			in:          &bodyOnlyUser{},
+			wanted:      ErrUnsupportedMediaType,
+		},
+		{
+			name:        "fields may bind from the url",
+			contentType: "text/plain",
+			in:          &jsonUser{},
+			wanted:      ErrMissingParam,
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("POST", "http://localhost:8080/users", strings.NewReader("name=bob"))
+		if test.contentType != "" {
+			r.Header.Add("Content-Type", test.contentType)
+		}
+		err := BindRequestParams(r, test.in)
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+	}
+	assert.Equal(t, http.StatusUnsupportedMediaType, StatusCode(ErrUnsupportedMediaType), "status code")
+}
+
+func TestRegisterBodyDecoder(t *testing.T) {
+	RegisterBodyDecoder("text/plain", func(r io.Reader) (map[string]interface{}, error) {
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"name": string(b)}, nil
+	})
+	defer func() {
+		bodyDecoders.Lock()
+		delete(bodyDecoders.m, "text/plain")
+		bodyDecoders.Unlock()
+	}()
+
+	r, _ := http.NewRequest("POST", "http://localhost:8080/users", strings.NewReader("bob"))
+	r.Header.Add("Content-Type", "Text/Plain")
+	u := &bodyOnlyUser{}
+	err := BindRequestParams(r, u)
+	assert.Nil(t, err, "custom decoder")
+	assert.Equal(t, "bob", u.Name, "custom decoder")
+}
@@ -0,0 +1,30 @@
+package middlewares
+
+import (
+	"context"
+	"net/http"
+)
+
+type bodyLimitKey struct{}
+
+// LimitBody limits the request body of the route to n bytes. Reading past
+// the limit fails and BindRequestParams reports it as ErrBodyTooLarge. The
+// limit options of BindRequestParams can lower but not raise it.
+func LimitBody(inner http.Handler, n int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > n {
+			http.Error(w, ErrBodyTooLarge.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+		}
+		ctx := context.WithValue(r.Context(), bodyLimitKey{}, n)
+		inner.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func bodyLimitFromContext(ctx context.Context) (int64, bool) {
+	n, ok := ctx.Value(bodyLimitKey{}).(int64)
+	return n, ok
+}
@@ -0,0 +1,162 @@
+package middlewares
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yikailee/golang/dto"
+)
+
+func TestBodyLimit(t *testing.T) {
+	bigJSON := `{"age":"28","name":"` + strings.Repeat("a", 2048) + `"}`
+	bigForm := url.Values{"age": []string{"28"}, "name": []string{strings.Repeat("a", 2048)}}.Encode()
+	testData := []struct {
+		name        string
+		body        string
+		contentType string
+		unknownSize bool
+		routeLimit  int64
+		opts        []Option
+		wanted      int
+	}{
+		{
+			name:        "json within route limit",
+			body:        bigJSON,
+			contentType: "application/json",
+			routeLimit:  4096,
+			wanted:      http.StatusOK,
+		},
+		{
+			name:        "json over route limit",
+			body:        bigJSON,
+			contentType: "application/json",
+			routeLimit:  1024,
+			wanted:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "json of unknown size over route limit",
+			body:        bigJSON,
+			contentType: "application/json",
+			unknownSize: true,
+			routeLimit:  1024,
+			wanted:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "dto limit lowers route limit",
+			body:        bigJSON,
+			contentType: "application/json",
+			unknownSize: true,
+			routeLimit:  4096,
+			opts:        []Option{MaxBodyBytes(1024)},
+			wanted:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "dto limit does not raise route limit",
+			body:        bigJSON,
+			contentType: "application/json",
+			unknownSize: true,
+			routeLimit:  1024,
+			opts:        []Option{MaxBodyBytes(4096)},
+			wanted:      http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:        "form over dto limit",
+			body:        bigForm,
+			contentType: "application/x-www-form-urlencoded",
+			unknownSize: true,
+			routeLimit:  4096,
+			opts:        []Option{MaxBodyBytes(1024)},
+			wanted:      http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, test := range testData {
+		handler := LimitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := BindRequestParams(r, &user1{}, test.opts...)
+			w.WriteHeader(StatusCode(err))
+		}), test.routeLimit)
+
+		r, _ := http.NewRequest("POST", "http://localhost:8080/users", bytes.NewBufferString(test.body))
+		if test.unknownSize {
+			r.ContentLength = -1
+		}
+		r.Header.Add("Content-Type", test.contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, test.wanted, w.Code, test.name)
+	}
+}
+
+type bigUpload struct {
+	dto.FieldSet
+	File *multipart.FileHeader `dto:"file" maxsize:"50MB"`
+}
+
+func TestMultipartBodyLimit(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "big.bin")
+	fw.Write(bytes.Repeat([]byte{0}, 12<<20))
+	mw.Close()
+
+	testData := []struct {
+		name       string
+		routeLimit int64
+		opts       []Option
+		wanted     int
+	}{
+		{
+			name:   "upload larger than the default body limit",
+			wanted: http.StatusOK,
+		},
+		{
+			name:   "upload over multipart limit",
+			opts:   []Option{MaxMultipartBytes(8 << 20)},
+			wanted: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "upload over dto limit",
+			opts:   []Option{MaxBodyBytes(8 << 20)},
+			wanted: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:       "upload over route limit",
+			routeLimit: 8 << 20,
+			wanted:     http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "multipart limit overrides dto limit",
+			opts:   []Option{MaxBodyBytes(8 << 20), MaxMultipartBytes(16 << 20)},
+			wanted: http.StatusOK,
+		},
+		{
+			name:       "multipart limit does not raise route limit",
+			routeLimit: 8 << 20,
+			opts:       []Option{MaxMultipartBytes(16 << 20)},
+			wanted:     http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, test := range testData {
+		var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			err := BindRequestParams(r, &bigUpload{}, test.opts...)
+			w.WriteHeader(StatusCode(err))
+		})
+		if test.routeLimit > 0 {
+			handler = LimitBody(handler, test.routeLimit)
+		}
+
+		r, _ := http.NewRequest("POST", "http://localhost:8080/uploads", bytes.NewReader(body.Bytes()))
+		r.ContentLength = -1
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, test.wanted, w.Code, test.name)
+	}
+}
@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrUnknownCollection = errors.New("unknown collection format")
+
+// Formats of the values of slice and array fields, selected by their
+// collection tag (collection:"csv"). They match the OpenAPI style and
+// explode options of query params:
+//
+//	multi     ids=1&ids=2, the default (form, explode)
+//	csv       ids=1,2 (form, no explode)
+//	ssv       ids=1%202 (spaceDelimited)
+//	pipes     ids=1|2 (pipeDelimited)
+//	brackets  ids[]=1&ids[]=2
+//	indexed   ids[0]=1&ids[1]=2
+//
+// Fields with the brackets and indexed formats still bind repeated plain
+// keys and JSON arrays.
+const (
+	CollectionMulti    = "multi"
+	CollectionCSV      = "csv"
+	CollectionSSV      = "ssv"
+	CollectionPipes    = "pipes"
+	CollectionBrackets = "brackets"
+	CollectionIndexed  = "indexed"
+)
+
+var collectionSeparators = map[string]string{
+	CollectionCSV:   ",",
+	CollectionSSV:   " ",
+	CollectionPipes: "|",
+}
+
+// NotEnoughValuesError is returned when a fixed size array gets fewer values
+// than its length. It matches ErrNotEnoughValue with errors.Is.
+type NotEnoughValuesError struct {
+	Expected int
+	Got      int
+}
+
+// NotEnoughValues returns the error of an array of length expected bound
+// from got values.
+func NotEnoughValues(expected, got int) error {
+	return &NotEnoughValuesError{Expected: expected, Got: got}
+}
+
+func (e *NotEnoughValuesError) Error() string {
+	return fmt.Sprintf("%v: expected %d, got %d", ErrNotEnoughValue, e.Expected, e.Got)
+}
+
+func (e *NotEnoughValuesError) Is(target error) bool {
+	return target == ErrNotEnoughValue
+}
+
+// checkCollection checks the collection tag of a field of type t.
+func checkCollection(t reflect.Type, format string) error {
+	switch format {
+	case "":
+		return nil
+	case CollectionMulti, CollectionCSV, CollectionSSV, CollectionPipes, CollectionBrackets, CollectionIndexed:
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownCollection, format)
+	}
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return fmt.Errorf("%w: %s on a %s field", ErrUnknownCollection, format, t.Kind())
+	}
+	return nil
+}
+
+// lookupCollection is lookup for the fields with a collection format.
+func (s *paramSources) lookupCollection(key, name, source, format string, body map[string]interface{}) (interface{}, bool, error) {
+	switch format {
+	case CollectionBrackets:
+		// normalizeKeys turned "ids[]" into "ids."
+		if v, ok, err := s.lookup(key+".", name, source, nil); err != nil || ok {
+			return v, ok, err
+		}
+	case CollectionIndexed:
+		if vs, ok := s.lookupIndexed(key, source); ok {
+			return vs, true, nil
+		}
+	}
+	v, ok, err := s.lookup(key, name, source, body)
+	if sep, split := collectionSeparators[format]; ok && split {
+		switch vv := v.(type) {
+		case []string:
+			v = splitValues(vv, sep)
+		case string:
+			v = splitValues([]string{vv}, sep)
+		}
+	}
+	return v, ok, err
+}
+
+// lookupIndexed collects the values of the "key.0", "key.1"... keys of the
+// first url or form source that has any, in index order.
+func (s *paramSources) lookupIndexed(key, source string) ([]string, bool) {
+	var maps []map[string][]string
+	switch source {
+	case "":
+		maps = []map[string][]string{s.path, s.query, s.form}
+	case SourcePath:
+		maps = []map[string][]string{s.path}
+	case SourceQuery:
+		maps = []map[string][]string{s.query}
+	case SourceForm:
+		maps = []map[string][]string{s.form}
+	}
+	prefix := key + "."
+	for _, m := range maps {
+		type indexed struct {
+			i  int
+			vs []string
+		}
+		var found []indexed
+		for k, vs := range m {
+			if !strings.HasPrefix(k, prefix) {
+				continue
+			}
+			i, err := strconv.Atoi(k[len(prefix):])
+			if err != nil || i < 0 {
+				continue
+			}
+			found = append(found, indexed{i, vs})
+		}
+		if found == nil {
+			continue
+		}
+		sort.Slice(found, func(a, b int) bool { return found[a].i < found[b].i })
+		var values []string
+		for _, f := range found {
+			values = append(values, f.vs...)
+		}
+		return values, true
+	}
+	return nil, false
+}
+
+// splitValues splits each value on sep, empty values have no element.
+func splitValues(vs []string, sep string) []string {
+	split := make([]string, 0, len(vs))
+	for _, v := range vs {
+		if v != "" {
+			split = append(split, strings.Split(v, sep)...)
+		}
+	}
+	return split
+}
@@ -0,0 +1,184 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yikailee/golang/dto"
+)
+
+type collectionUser struct {
+	dto.FieldSet
+	Multi    []int     `dto:"multi" required:"false" collection:"multi"`
+	CSV      []int     `dto:"csv" required:"false" collection:"csv"`
+	SSV      []string  `dto:"ssv" required:"false" collection:"ssv"`
+	Pipes    [2]int    `dto:"pipes" required:"false" collection:"pipes"`
+	Brackets []string  `dto:"brackets" required:"false" collection:"brackets"`
+	Indexed  []float64 `dto:"indexed" required:"false" collection:"indexed"`
+}
+
+func TestBindCollectionHappyPath(t *testing.T) {
+	testData := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		wanted      *collectionUser
+		wantedSet   []string
+	}{
+		{
+			name: "multi",
+			url:  "http://localhost:8080/users?multi=1&multi=2",
+			wanted: &collectionUser{
+				Multi: []int{1, 2},
+			},
+			wantedSet: []string{"multi"},
+		},
+		{
+			name: "delimited",
+			url:  "http://localhost:8080/users?csv=1,2,3&ssv=a%20b&pipes=4|5|6",
+			wanted: &collectionUser{
+				CSV:   []int{1, 2, 3},
+				SSV:   []string{"a", "b"},
+				Pipes: [2]int{4, 5},
+			},
+			wantedSet: []string{"csv", "ssv", "pipes"},
+		},
+		{
+			name: "csv repeated",
+			url:  "http://localhost:8080/users?csv=1,2&csv=3",
+			wanted: &collectionUser{
+				CSV: []int{1, 2, 3},
+			},
+			wantedSet: []string{"csv"},
+		},
+		{
+			name: "brackets and indexes",
+			url:  "http://localhost:8080/users?brackets[]=a&brackets[]=b&indexed[1]=2.5&indexed[0]=1.5&indexed[10]=3",
+			wanted: &collectionUser{
+				Brackets: []string{"a", "b"},
+				Indexed:  []float64{1.5, 2.5, 3},
+			},
+			wantedSet: []string{"brackets", "indexed"},
+		},
+		{
+			name: "plain keys fallback",
+			url:  "http://localhost:8080/users?brackets=a&indexed=1",
+			wanted: &collectionUser{
+				Brackets: []string{"a"},
+				Indexed:  []float64{1},
+			},
+			wantedSet: []string{"brackets", "indexed"},
+		},
+		{
+			name:        "form indexes",
+			url:         "http://localhost:8080/users",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "indexed[0]=1&indexed[1]=2",
+			wanted: &collectionUser{
+				Indexed: []float64{1, 2},
+			},
+			wantedSet: []string{"indexed"},
+		},
+		{
+			name:        "json arrays and strings",
+			url:         "http://localhost:8080/users",
+			contentType: "application/json",
+			body:        `{"csv": "1,2", "indexed": [1, 2]}`,
+			wanted: &collectionUser{
+				CSV:     []int{1, 2},
+				Indexed: []float64{1, 2},
+			},
+			wantedSet: []string{"csv", "indexed"},
+		},
+	}
+
+	for _, test := range testData {
+		method := "GET"
+		if test.body != "" {
+			method = "POST"
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Add("Content-Type", test.contentType)
+		}
+		u := &collectionUser{}
+		err := BindRequestParams(r, u)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wantedSet, u.SetFields(), test.name)
+		u.FieldSet = dto.FieldSet{}
+		assert.Equal(t, test.wanted, u, test.name)
+	}
+}
+
+type arrayUser struct {
+	dto.FieldSet
+	Point [3]int `dto:"point" collection:"csv"`
+}
+
+func TestBindCollectionNotEnoughValues(t *testing.T) {
+	testData := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		wanted      string
+	}{
+		{
+			name:   "csv",
+			url:    "http://localhost:8080/users?point=1,2",
+			wanted: "point: not enough values: expected 3, got 2",
+		},
+		{
+			name:        "json array",
+			url:         "http://localhost:8080/users",
+			contentType: "application/json",
+			body:        `{"point": [1]}`,
+			wanted:      "point: not enough values: expected 3, got 1",
+		},
+	}
+
+	for _, test := range testData {
+		method := "GET"
+		if test.body != "" {
+			method = "POST"
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Add("Content-Type", test.contentType)
+		}
+		err := BindRequestParams(r, &arrayUser{})
+		assert.True(t, errors.Is(err, ErrNotEnoughValue), test.name)
+		var notEnough *NotEnoughValuesError
+		assert.True(t, errors.As(err, &notEnough), test.name)
+		if err != nil {
+			assert.Equal(t, test.wanted, err.Error(), test.name)
+		}
+	}
+}
+
+type badCollectionUser struct {
+	dto.FieldSet
+	ID int `dto:"id" collection:"csv"`
+}
+
+func TestLookupCollection(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/users?ids=1|2&tags[0]=a&tags[1]=b", nil)
+	values, err := NewBindValues(r, nil)
+	assert.Nil(t, err, "new bind values")
+
+	v, ok, err := values.LookupCollection("ids", "query", CollectionPipes)
+	assert.True(t, ok && err == nil, "pipes")
+	n, _ := v.Len()
+	assert.Equal(t, 2, n, "pipes")
+
+	v, ok, err = values.LookupCollection("tags", "", CollectionIndexed)
+	assert.True(t, ok && err == nil, "indexed")
+	s, _ := v.Index(1).String()
+	assert.Equal(t, "b", s, "indexed")
+
+	assert.True(t, errors.Is(Register(&badCollectionUser{}), ErrUnknownCollection), "collection on a scalar")
+}
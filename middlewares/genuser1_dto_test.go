@@ -0,0 +1,93 @@
+// Code generated by dtogen; DO NOT EDIT.
+
+package middlewares
+
+import (
+	"net/http"
+)
+
+func (u *genUser1) AlreadySet(dtoName string) bool {
+	_, ok := u.setItems[dtoName]
+	return ok
+}
+
+func (u *genUser1) MarkSet(dtoName string) {
+	if u.setItems == nil {
+		u.setItems = make(map[string]bool)
+	}
+	u.setItems[dtoName] = true
+}
+
+func (u *genUser1) MarkAllUnset() {
+	u.setItems = make(map[string]bool)
+}
+
+// Bind binds the params of r into u like BindRequestParams.
+func (u *genUser1) Bind(r *http.Request, opts ...Option) error {
+	values, err := NewBindValues(r, []string{"name", "age", "hobby"}, opts...)
+	if err != nil {
+		return err
+	}
+	if err := values.CheckUnknownParams("name", "age", "hobby"); err != nil {
+		return err
+	}
+	if v, ok, err := values.Lookup("name", ""); err != nil {
+		return err
+	} else if ok && u.dtoBindName(v) == nil {
+		u.MarkSet("name")
+	}
+	if v, ok, err := values.Lookup("age", ""); err != nil {
+		return err
+	} else if !ok {
+		return MissingParam("age")
+	} else if err := u.dtoBindAge(v); err != nil {
+		return FieldError("age", err)
+	} else {
+		u.MarkSet("age")
+	}
+	if v, ok, err := values.Lookup("hobby", ""); err != nil {
+		return err
+	} else if ok && u.dtoBindHobby(v) == nil {
+		u.MarkSet("hobby")
+	}
+	return nil
+}
+
+func (u *genUser1) dtoBindName(v BindValue) error {
+	x, err := v.String()
+	if err != nil {
+		return err
+	}
+	u.Name = x
+	return nil
+}
+
+func (u *genUser1) dtoBindAge(v BindValue) error {
+	x, err := v.Int(0)
+	if err != nil {
+		return err
+	}
+	u.Age = int(x)
+	return nil
+}
+
+func (u *genUser1) dtoBindHobby(v BindValue) error {
+	if v.IsNull() {
+		u.Hobby = nil
+		return nil
+	}
+	n, err := v.Len()
+	if err != nil {
+		return err
+	}
+	s := make([]string, n)
+	for i := range s {
+		x, err := v.Index(i).String()
+		if err != nil {
+			return err
+		}
+		s[i] = x
+	}
+	u.Hobby = s
+	return nil
+}
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/yikailee/golang/dto"
+)
+
+// Validator is implemented by DTOs checking their bound values, Handle
+// answers 400 Bad Request when Validate fails.
+type Validator interface {
+	Validate() error
+}
+
+// StatusCoder is implemented by the errors of Handle functions choosing the
+// HTTP status code of the response and exposing their message. The other
+// errors are answered with a bare 500 Internal Server Error.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Bind allocates a T, which must be a struct, and binds r into it like
+// BindRequestParams. T does not need to implement dto.ValidRequestDTO, the
+// set fields of those which do not are not tracked.
+func Bind[T any](r *http.Request, opts ...Option) (*T, error) {
+	in := new(T)
+	if v, ok := any(in).(dto.ValidRequestDTO); ok {
+		return in, BindRequestParams(r, v, opts...)
+	}
+	return in, bindRequestParams(r, reflect.ValueOf(in), discardMarks{}, opts)
+}
+
+// Handle adapts fn to a http.Handler: it binds the request into a T,
+// validates it when T implements Validator, invokes fn and writes its result
+// as JSON, or the error as a dto.GeneralRsp. A nil result is answered with
+// 204 No Content.
+func Handle[T any](fn func(ctx context.Context, in *T) (any, error), opts ...Option) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in, err := Bind[T](r, opts...)
+		if err != nil {
+			writeError(w, StatusCode(err), err.Error())
+			return
+		}
+		if v, ok := any(in).(Validator); ok {
+			if err := v.Validate(); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		rsp, err := fn(r.Context(), in)
+		if err != nil {
+			var sc StatusCoder
+			if errors.As(err, &sc) {
+				writeError(w, sc.StatusCode(), err.Error())
+				return
+			}
+			// the message may hold internal details
+			writeError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+			return
+		}
+		if rsp == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, rsp)
+	})
+}
+
+func writeError(w http.ResponseWriter, code int, message string) {
+	writeJSON(w, code, dto.GeneralRsp{Message: message})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
@@ -0,0 +1,121 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type plainUser struct {
+	Name string `dto:"name"`
+	Age  int64  `dto:"age" required:"false"`
+}
+
+func (u *plainUser) Validate() error {
+	if u.Age < 0 {
+		return errors.New("age must not be negative")
+	}
+	return nil
+}
+
+type notFoundError struct{}
+
+func (notFoundError) Error() string   { return "user not found" }
+func (notFoundError) StatusCode() int { return http.StatusNotFound }
+
+func TestBind(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/users?name=bob&age=28", nil)
+	u, err := Bind[plainUser](r)
+	assert.Nil(t, err, "plain struct")
+	assert.Equal(t, &plainUser{Name: "bob", Age: 28}, u, "plain struct")
+
+	r, _ = http.NewRequest("GET", "http://localhost:8080/users?age=28&hobby=sport", nil)
+	u1, err := Bind[user1](r)
+	assert.Nil(t, err, "dto")
+	assert.Equal(t, map[string]bool{"age": true, "hobby": true}, u1.setItems, "dto")
+
+	r, _ = http.NewRequest("GET", "http://localhost:8080/users?age=28", nil)
+	_, err = Bind[plainUser](r)
+	assert.True(t, errors.Is(err, ErrMissingParam), "missing param")
+
+	_, err = Bind[int](r)
+	assert.Equal(t, ErrUnhandleType, err, "not a struct")
+}
+
+func TestHandle(t *testing.T) {
+	handler := Handle(func(ctx context.Context, in *plainUser) (any, error) {
+		switch in.Name {
+		case "nobody":
+			return nil, notFoundError{}
+		case "broken":
+			return nil, errors.New("broken: dial tcp 10.0.0.7:5432")
+		case "wrapped":
+			return nil, fmt.Errorf("lookup: %w", notFoundError{})
+		case "empty":
+			return nil, nil
+		}
+		return map[string]interface{}{"greeting": "hello " + in.Name}, nil
+	})
+
+	testData := []struct {
+		name       string
+		url        string
+		wantedCode int
+		wantedBody string
+	}{
+		{
+			name:       "happy path",
+			url:        "http://localhost:8080/users?name=bob",
+			wantedCode: http.StatusOK,
+			wantedBody: `{"greeting":"hello bob"}`,
+		},
+		{
+			name:       "empty result",
+			url:        "http://localhost:8080/users?name=empty",
+			wantedCode: http.StatusNoContent,
+		},
+		{
+			name:       "bind error",
+			url:        "http://localhost:8080/users",
+			wantedCode: http.StatusBadRequest,
+			wantedBody: `{"message":"missing required param: name"}`,
+		},
+		{
+			name:       "validation error",
+			url:        "http://localhost:8080/users?name=bob&age=-1",
+			wantedCode: http.StatusBadRequest,
+			wantedBody: `{"message":"age must not be negative"}`,
+		},
+		{
+			name:       "handler error with status",
+			url:        "http://localhost:8080/users?name=nobody",
+			wantedCode: http.StatusNotFound,
+			wantedBody: `{"message":"user not found"}`,
+		},
+		{
+			name:       "wrapped handler error with status",
+			url:        "http://localhost:8080/users?name=wrapped",
+			wantedCode: http.StatusNotFound,
+			wantedBody: `{"message":"lookup: user not found"}`,
+		},
+		{
+			name:       "handler error",
+			url:        "http://localhost:8080/users?name=broken",
+			wantedCode: http.StatusInternalServerError,
+			wantedBody: `{"message":"Internal Server Error"}`,
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("GET", test.url, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		assert.Equal(t, test.wantedCode, w.Code, test.name)
+		assert.Equal(t, test.wantedBody, w.Body.String(), test.name)
+	}
+}
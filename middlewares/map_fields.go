@@ -0,0 +1,156 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var ErrTooManyKeys = errors.New("too many map keys")
+
+// Syntaxes of the params of map fields, selected by their mapstyle tag:
+// MapBrackets binds meta[color]=red, the default, and MapDot binds
+// meta.color=red. The maxkeys tag caps the number of keys of a map field,
+// defaultMaxMapKeys by default.
+const (
+	MapBrackets = "brackets"
+	MapDot      = "dot"
+)
+
+const defaultMaxMapKeys = 64
+
+type mapRules struct {
+	style   string
+	maxKeys int
+	elem    valuesSetter
+}
+
+// mapValues are the values of a map field collected from the url or form
+// params, by map key.
+type mapValues map[string][]string
+
+func parseMapRules(t reflect.Type, tag reflect.StructTag) (*mapRules, error) {
+	if t.Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("%w: map key %s", ErrUnhandleType, t.Key())
+	}
+	elem := t.Elem()
+	kind := elem.Kind()
+	if kind == reflect.Slice || kind == reflect.Array {
+		kind = elem.Elem().Kind()
+	}
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64, reflect.Bool, reflect.String:
+	default:
+		return nil, fmt.Errorf("%w: map value %s", ErrUnhandleType, elem)
+	}
+
+	rules := &mapRules{style: MapBrackets, maxKeys: defaultMaxMapKeys, elem: newValuesSetter(elem)}
+	switch style := tag.Get("mapstyle"); style {
+	case "":
+	case MapBrackets, MapDot:
+		rules.style = style
+	default:
+		return nil, fmt.Errorf("unknown map style: %s", style)
+	}
+	if max, ok := tag.Lookup("maxkeys"); ok {
+		n, err := strconv.Atoi(max)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("bad maxkeys %q", max)
+		}
+		rules.maxKeys = n
+	}
+	return rules, nil
+}
+
+// lookupMap collects the params of the map field key written in style from
+// the first url or form source that has any, or else returns the object of
+// the dto name in the body.
+func (s *paramSources) lookupMap(key, name, source, style string, body map[string]interface{}) (interface{}, bool, error) {
+	var sources []map[string][]string
+	switch source {
+	case "":
+		sources = []map[string][]string{s.rawPath, s.rawQuery, s.rawForm}
+	case SourcePath:
+		sources = []map[string][]string{s.rawPath}
+	case SourceQuery:
+		sources = []map[string][]string{s.rawQuery}
+	case SourceForm:
+		sources = []map[string][]string{s.rawForm}
+	case SourceBody:
+	default:
+		return nil, false, fmt.Errorf("%w: %s for a map", ErrUnknownSource, source)
+	}
+
+	prefix := key + "."
+	for _, values := range sources {
+		var mv mapValues
+		for raw, vs := range values {
+			mk, ok := mapKey(raw, prefix, style)
+			if !ok {
+				continue
+			}
+			if mv == nil {
+				mv = make(mapValues)
+			}
+			mv[mk] = append(mv[mk], vs...)
+		}
+		if mv != nil {
+			return mv, true, nil
+		}
+	}
+	if source == "" || source == SourceBody {
+		jv, ok := body[name]
+		return jv, ok, nil
+	}
+	return nil, false, nil
+}
+
+// mapKey returns the map key of the param raw when it is written in style
+// under the normalized prefix ("filter.meta."), like filter[meta][color] or
+// filter.meta.color.
+func mapKey(raw, prefix, style string) (string, bool) {
+	nk := normalizeKey(raw)
+	if !strings.HasPrefix(nk, prefix) || len(nk) == len(prefix) {
+		return "", false
+	}
+	mk := nk[len(prefix):]
+	switch style {
+	case MapBrackets:
+		return mk, strings.HasSuffix(raw, "["+mk+"]")
+	case MapDot:
+		return mk, strings.HasSuffix(raw, "."+mk)
+	}
+	return "", false
+}
+
+// setMapValue sets rVal to a new map holding the converted values of mv.
+func setMapValue(rVal reflect.Value, mv mapValues, rules *mapRules) error {
+	if len(mv) > rules.maxKeys {
+		return fmt.Errorf("%w: %d, at most %d", ErrTooManyKeys, len(mv), rules.maxKeys)
+	}
+	if !rVal.CanSet() {
+		return ErrCantSetValue
+	}
+	t := rVal.Type()
+	m := reflect.MakeMapWithSize(t, len(mv))
+	for k, vs := range mv {
+		elem := reflect.New(t.Elem()).Elem()
+		if err := rules.elem(elem, vs); err != nil {
+			return fmt.Errorf("%s: %w", k, err)
+		}
+		m.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+	}
+	rVal.Set(m)
+	return nil
+}
+
+// checkMapKeys caps the number of keys of a JSON object bound into a map.
+func checkMapKeys(jv interface{}, rules *mapRules) error {
+	if obj, ok := jv.(map[string]interface{}); ok && len(obj) > rules.maxKeys {
+		return fmt.Errorf("%w: %d, at most %d", ErrTooManyKeys, len(obj), rules.maxKeys)
+	}
+	return nil
+}
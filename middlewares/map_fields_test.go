@@ -0,0 +1,185 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yikailee/golang/dto"
+)
+
+type mapUser struct {
+	dto.FieldSet
+	Meta   map[string]string   `dto:"meta" required:"false"`
+	Limits map[string]int      `dto:"limits" required:"false" mapstyle:"dot" maxkeys:"2"`
+	Tags   map[string][]string `dto:"tags,query" required:"false"`
+}
+
+type nestedMapUser struct {
+	dto.FieldSet
+	Filter struct {
+		Labels map[string]string `dto:"labels"`
+	} `dto:"filter"`
+}
+
+func TestBindMapFieldsHappyPath(t *testing.T) {
+	testData := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		wanted      *mapUser
+		wantedSet   []string
+	}{
+		{
+			name: "bracket and dot keys",
+			url:  "http://localhost:8080/users?meta[color]=red&meta[size]=xl&limits.cpu=2&limits.mem=512",
+			wanted: &mapUser{
+				Meta:   map[string]string{"color": "red", "size": "xl"},
+				Limits: map[string]int{"cpu": 2, "mem": 512},
+			},
+			wantedSet: []string{"meta", "limits"},
+		},
+		{
+			name:      "keys in the other style are ignored",
+			url:       "http://localhost:8080/users?meta.color=red&limits[cpu]=2",
+			wanted:    &mapUser{},
+			wantedSet: []string{},
+		},
+		{
+			name: "repeated keys",
+			url:  "http://localhost:8080/users?tags[env]=prod&tags[env]=eu",
+			wanted: &mapUser{
+				Tags: map[string][]string{"env": {"prod", "eu"}},
+			},
+			wantedSet: []string{"tags"},
+		},
+		{
+			name:        "form keys",
+			url:         "http://localhost:8080/users",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "meta[color]=blue",
+			wanted: &mapUser{
+				Meta: map[string]string{"color": "blue"},
+			},
+			wantedSet: []string{"meta"},
+		},
+		{
+			name:        "json objects",
+			url:         "http://localhost:8080/users",
+			contentType: "application/json",
+			body:        `{"meta": {"color": "red"}, "limits": {"cpu": 2}}`,
+			wanted: &mapUser{
+				Meta:   map[string]string{"color": "red"},
+				Limits: map[string]int{"cpu": 2},
+			},
+			wantedSet: []string{"meta", "limits"},
+		},
+	}
+
+	for _, test := range testData {
+		method := "GET"
+		if test.body != "" {
+			method = "POST"
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Add("Content-Type", test.contentType)
+		}
+		u := &mapUser{}
+		err := BindRequestParams(r, u)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wantedSet, u.SetFields(), test.name)
+		u.FieldSet = dto.FieldSet{}
+		assert.Equal(t, test.wanted, u, test.name)
+	}
+
+	r, _ := http.NewRequest("GET", "http://localhost:8080/users?filter[labels][app]=web&filter.labels[tier]=front", nil)
+	u := &nestedMapUser{}
+	err := BindRequestParams(r, u, Strict())
+	assert.Nil(t, err, "nested map")
+	assert.Equal(t, map[string]string{"app": "web", "tier": "front"}, u.Filter.Labels, "nested map")
+}
+
+type cappedMapUser struct {
+	dto.FieldSet
+	Limits map[string]int `dto:"limits" mapstyle:"dot" maxkeys:"2"`
+}
+
+func TestBindMapFieldsError(t *testing.T) {
+	testData := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		in          dto.ValidRequestDTO
+		wanted      error
+	}{
+		{
+			name:   "too many keys",
+			url:    "http://localhost:8080/users?limits.cpu=1&limits.mem=2&limits.disk=3",
+			in:     &cappedMapUser{},
+			wanted: ErrTooManyKeys,
+		},
+		{
+			name:        "too many json keys",
+			url:         "http://localhost:8080/users",
+			contentType: "application/json",
+			body:        `{"limits": {"cpu": 1, "mem": 2, "disk": 3}}`,
+			in:          &cappedMapUser{},
+			wanted:      ErrTooManyKeys,
+		},
+		{
+			name:   "bad value",
+			url:    "http://localhost:8080/users?limits.cpu=two",
+			in:     &cappedMapUser{},
+			wanted: strconv.ErrSyntax,
+		},
+		{
+			name:        "json value of the wrong type",
+			url:         "http://localhost:8080/users",
+			contentType: "application/json",
+			body:        `{"filter": {"labels": {"app": ["web"]}}}`,
+			in:          &nestedMapUser{},
+			wanted:      ErrErrorType,
+		},
+		{
+			name:   "missing required map",
+			url:    "http://localhost:8080/users?filter[label]=x",
+			in:     &nestedMapUser{},
+			wanted: ErrMissingParam,
+		},
+	}
+
+	for _, test := range testData {
+		method := "GET"
+		if test.body != "" {
+			method = "POST"
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Add("Content-Type", test.contentType)
+		}
+		err := BindRequestParams(r, test.in)
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+	}
+}
+
+type badMapUser struct {
+	dto.FieldSet
+	Meta map[string]string `dto:"meta,header"`
+}
+
+type badMapValueUser struct {
+	dto.FieldSet
+	Meta map[string]struct{} `dto:"meta"`
+}
+
+func TestRegisterMapFields(t *testing.T) {
+	assert.True(t, errors.Is(Register(&badMapUser{}), ErrUnknownSource), "header map")
+	assert.True(t, errors.Is(Register(&badMapValueUser{}), ErrUnhandleType), "struct values")
+	assert.Nil(t, Register(&mapUser{}), "map user")
+}
@@ -0,0 +1,181 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrFileTooLarge = errors.New("uploaded file too large")
+	ErrFileType     = errors.New("uploaded file type not allowed")
+	ErrTooManyFiles = errors.New("too many uploaded files")
+)
+
+var (
+	fileHeaderType      = reflect.TypeOf((*multipart.FileHeader)(nil))
+	fileHeaderSliceType = reflect.TypeOf([]*multipart.FileHeader(nil))
+)
+
+func isFileType(t reflect.Type) bool {
+	return t == fileHeaderType || t == fileHeaderSliceType
+}
+
+// fileRules holds the maxsize, accept and maxfiles tags of a file field.
+type fileRules struct {
+	maxSize  int64
+	accept   []string
+	maxFiles int
+}
+
+func parseFileRules(tag reflect.StructTag) (rules fileRules, err error) {
+	if s, ok := tag.Lookup("maxsize"); ok {
+		if rules.maxSize, err = parseByteSize(s); err != nil {
+			return rules, fmt.Errorf("bad maxsize %q: %w", s, err)
+		}
+	}
+	if s, ok := tag.Lookup("maxfiles"); ok {
+		if rules.maxFiles, err = strconv.Atoi(s); err != nil {
+			return rules, fmt.Errorf("bad maxfiles %q: %w", s, err)
+		}
+	}
+	if s, ok := tag.Lookup("accept"); ok {
+		for _, mt := range strings.Split(s, ",") {
+			rules.accept = append(rules.accept, strings.TrimSpace(mt))
+		}
+	}
+	return rules, nil
+}
+
+// parseByteSize parses sizes like "512", "64KB", "2MB" or "1GB".
+func parseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			n, err := strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), 10, 64)
+			return n * u.size, err
+		}
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+// setFileValue sets a *multipart.FileHeader or []*multipart.FileHeader field
+// after checking the uploaded files against the rules of its tags.
+func setFileValue(rVal reflect.Value, fhs []*multipart.FileHeader, rules *fileRules) error {
+	if !rVal.CanSet() {
+		return ErrCantSetValue
+	}
+	if len(fhs) == 0 {
+		return ErrEmptyValue
+	}
+	if rules.maxFiles > 0 && len(fhs) > rules.maxFiles {
+		return fmt.Errorf("%w: %d > %d", ErrTooManyFiles, len(fhs), rules.maxFiles)
+	}
+	for _, fh := range fhs {
+		if rules.maxSize > 0 && fh.Size > rules.maxSize {
+			return fmt.Errorf("%w: %s", ErrFileTooLarge, fh.Filename)
+		}
+		if len(rules.accept) > 0 {
+			ct, err := sniffContentType(fh)
+			if err != nil {
+				return err
+			}
+			if !acceptMediaType(rules.accept, ct) {
+				return fmt.Errorf("%w: %s is %s", ErrFileType, fh.Filename, ct)
+			}
+		}
+	}
+
+	if rVal.Type() == fileHeaderType {
+		rVal.Set(reflect.ValueOf(fhs[0]))
+		return nil
+	}
+	rVal.Set(reflect.ValueOf(fhs))
+	return nil
+}
+
+// sniffContentType detects the media type from the file content, the type
+// declared by the client is ignored.
+func sniffContentType(fh *multipart.FileHeader) (string, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := f.Read(buf)
+	if err != nil && n == 0 && fh.Size > 0 {
+		return "", err
+	}
+	mt, _, err := mime.ParseMediaType(http.DetectContentType(buf[:n]))
+	return mt, err
+}
+
+func acceptMediaType(accept []string, mt string) bool {
+	for _, a := range accept {
+		if a == mt || a == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(a, "/*") && strings.HasPrefix(mt, strings.TrimSuffix(a, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+type multipartCleanupKey struct{}
+
+type multipartCleanup struct {
+	sync.Mutex
+	forms []*multipart.Form
+}
+
+// CleanupMultipart removes the temporary files of the multipart forms parsed
+// by BindRequestParams once inner returns, even when inner bound a copy of
+// the request made with WithContext.
+func CleanupMultipart(inner http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := &multipartCleanup{}
+		defer func() {
+			c.Lock()
+			defer c.Unlock()
+			for _, f := range c.forms {
+				f.RemoveAll()
+			}
+		}()
+		ctx := context.WithValue(r.Context(), multipartCleanupKey{}, c)
+		inner.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func trackMultipartForm(r *http.Request) {
+	c, ok := r.Context().Value(multipartCleanupKey{}).(*multipartCleanup)
+	if !ok || r.MultipartForm == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	for _, f := range c.forms {
+		if f == r.MultipartForm {
+			return
+		}
+	}
+	c.forms = append(c.forms, r.MultipartForm)
+}
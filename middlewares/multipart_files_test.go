@@ -0,0 +1,140 @@
+package middlewares
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var pngContent = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 600)...)
+
+type uploadUser struct {
+	Name     string                  `dto:"name"`
+	Avatar   *multipart.FileHeader   `dto:"avatar" accept:"image/png,image/jpeg" maxsize:"1KB"`
+	Photos   []*multipart.FileHeader `dto:"photos" required:"false" accept:"image/*" maxfiles:"2"`
+	setItems map[string]bool
+}
+
+func (u *uploadUser) AlreadySet(dtoName string) bool {
+	_, ok := u.setItems[dtoName]
+	return ok
+}
+
+func (u *uploadUser) MarkSet(dtoName string) {
+	if u.setItems == nil {
+		u.setItems = make(map[string]bool)
+	}
+	u.setItems[dtoName] = true
+}
+
+func (u *uploadUser) MarkAllUnset() {
+	u.setItems = make(map[string]bool)
+}
+
+type uploadFile struct {
+	field, name string
+	content     []byte
+}
+
+func newMultipartRequest(fields map[string]string, files []uploadFile) *http.Request {
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	for _, f := range files {
+		fw, _ := mw.CreateFormFile(f.field, f.name)
+		fw.Write(f.content)
+	}
+	mw.Close()
+	r, _ := http.NewRequest("POST", "http://localhost:8080/users", &b)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+func TestMultipartFilesHappyPath(t *testing.T) {
+	r := newMultipartRequest(map[string]string{"name": "bob"}, []uploadFile{
+		{"avatar", "avatar.png", pngContent},
+		{"photos", "a.png", pngContent},
+		{"photos", "b.png", pngContent},
+	})
+	u := &uploadUser{}
+	err := BindRequestParams(r, u)
+	assert.Nil(t, err, "happy path")
+	assert.Equal(t, "bob", u.Name, "happy path")
+	assert.Equal(t, "avatar.png", u.Avatar.Filename, "happy path")
+	assert.Equal(t, 2, len(u.Photos), "happy path")
+	assert.Equal(t, map[string]bool{"name": true, "avatar": true, "photos": true}, u.setItems, "happy path")
+}
+
+func TestMultipartFilesError(t *testing.T) {
+	testData := []struct {
+		name   string
+		files  []uploadFile
+		wanted error
+		status int
+	}{
+		{
+			name:   "missing file",
+			wanted: ErrMissingParam,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "declared png but sniffed text",
+			files:  []uploadFile{{"avatar", "avatar.png", []byte("just some text")}},
+			wanted: ErrFileType,
+			status: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:   "file too large",
+			files:  []uploadFile{{"avatar", "avatar.png", append(pngContent, pngContent...)}},
+			wanted: ErrFileTooLarge,
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, test := range testData {
+		r := newMultipartRequest(map[string]string{"name": "bob"}, test.files)
+		err := BindRequestParams(r, &uploadUser{})
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+		assert.Equal(t, test.status, StatusCode(err), test.name)
+	}
+
+	// optional fields still fail to bind with too many files, but do not
+	// fail the request
+	r := newMultipartRequest(map[string]string{"name": "bob"}, []uploadFile{
+		{"avatar", "avatar.png", pngContent},
+		{"photos", "a.png", pngContent},
+		{"photos", "b.png", pngContent},
+		{"photos", "c.png", pngContent},
+	})
+	u := &uploadUser{}
+	assert.Nil(t, BindRequestParams(r, u), "too many optional files")
+	assert.Nil(t, u.Photos, "too many optional files")
+}
+
+func TestCleanupMultipart(t *testing.T) {
+	var avatar *multipart.FileHeader
+	handler := CleanupMultipart(LimitBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u := &uploadUser{}
+		err := BindRequestParams(r, u, MaxMemory(1))
+		assert.Nil(t, err, "bind")
+		avatar = u.Avatar
+		f, err := avatar.Open()
+		assert.Nil(t, err, "temporary file available in handler")
+		f.Close()
+	}), 1<<20))
+
+	r := newMultipartRequest(map[string]string{"name": strings.Repeat("a", 10)}, []uploadFile{
+		{"avatar", "avatar.png", pngContent},
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	_, err := avatar.Open()
+	assert.NotNil(t, err, "temporary file removed after handler")
+}
@@ -0,0 +1,167 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Sources a dto field can be bound from, selected by the second element of
+// its dto tag (dto:"X-Tenant,header"). Fields without a source look the name
+// up in sourcePrecedence order, except multipart file fields which default
+// to the file source.
+const (
+	SourcePath    = "path"
+	SourceQuery   = "query"
+	SourceForm    = "form"
+	SourceBody    = "body"
+	SourceHeader  = "header"
+	SourceCookie  = "cookie"
+	SourceContext = "context"
+	SourceFile    = "file"
+)
+
+var sourcePrecedence = []string{SourcePath, SourceQuery, SourceForm, SourceBody}
+
+// ContextKey is the type of the request context keys read by dto fields
+// with the context source.
+type ContextKey string
+
+type paramSources struct {
+	r               *http.Request
+	path            map[string][]string
+	query           map[string][]string
+	form            map[string][]string
+	rejectAmbiguous bool
+
+	// the keys as sent, for map fields
+	rawPath  map[string][]string
+	rawQuery map[string][]string
+	rawForm  map[string][]string
+}
+
+func newParamSources(r *http.Request, o *parseOptions) *paramSources {
+	path, _ := parseUrlEmbededParams(r)
+	query := r.URL.Query()
+	return &paramSources{
+		r:               r,
+		path:            normalizeKeys(path),
+		query:           normalizeKeys(query),
+		form:            normalizeKeys(r.PostForm),
+		rejectAmbiguous: o.rejectAmbiguous,
+		rawPath:         path,
+		rawQuery:        query,
+		rawForm:         r.PostForm,
+	}
+}
+
+// parseDTOTag splits a dto tag into the dto name and its source.
+func parseDTOTag(tag string) (name, source string) {
+	if i := strings.IndexByte(tag, ','); i >= 0 {
+		return tag[:i], strings.TrimSpace(tag[i+1:])
+	}
+	return tag, ""
+}
+
+func (s *paramSources) hasValuesWithPrefix(prefix string) bool {
+	return hasValuesWithPrefix(s.path, prefix) ||
+		hasValuesWithPrefix(s.query, prefix) ||
+		hasValuesWithPrefix(s.form, prefix)
+}
+
+// lookup finds the value of the dto name in source. key is the name prefixed
+// with the dto names of the enclosing structs, used by the url and form
+// sources, and body is the JSON object of the enclosing struct. The value is
+// either a []string or a decoded JSON value.
+func (s *paramSources) lookup(key, name, source string, body map[string]interface{}) (interface{}, bool, error) {
+	switch source {
+	case "":
+		var (
+			value interface{}
+			found []string
+		)
+		for _, src := range sourcePrecedence {
+			v, ok, _ := s.lookup(key, name, src, body)
+			if !ok {
+				continue
+			}
+			if found == nil {
+				value = v
+			}
+			found = append(found, src)
+		}
+		if len(found) > 1 && s.rejectAmbiguous {
+			return nil, false, fmt.Errorf("%w: %s found in %s", ErrAmbiguousParam, key, strings.Join(found, ", "))
+		}
+		return value, found != nil, nil
+	case SourcePath:
+		vs, ok := s.path[key]
+		return vs, ok, nil
+	case SourceQuery:
+		vs, ok := s.query[key]
+		return vs, ok, nil
+	case SourceForm:
+		vs, ok := s.form[key]
+		return vs, ok, nil
+	case SourceBody:
+		jv, ok := body[name]
+		return jv, ok, nil
+	case SourceHeader:
+		if s.r == nil {
+			return nil, false, nil
+		}
+		vs, ok := s.r.Header[http.CanonicalHeaderKey(name)]
+		return vs, ok, nil
+	case SourceCookie:
+		if s.r == nil {
+			return nil, false, nil
+		}
+		var vs []string
+		for _, c := range s.r.Cookies() {
+			if c.Name == name {
+				vs = append(vs, c.Value)
+			}
+		}
+		return vs, vs != nil, nil
+	case SourceContext:
+		if s.r == nil {
+			return nil, false, nil
+		}
+		v := s.r.Context().Value(ContextKey(name))
+		return contextValue{v}, v != nil, nil
+	case SourceFile:
+		if s.r == nil || s.r.MultipartForm == nil {
+			return nil, false, nil
+		}
+		fhs, ok := s.r.MultipartForm.File[key]
+		return fhs, ok, nil
+	}
+	return nil, false, fmt.Errorf("%w: %s", ErrUnknownSource, source)
+}
+
+// contextValue wraps a request context value, which is assigned as is when
+// its type matches the field.
+type contextValue struct {
+	v interface{}
+}
+
+func setContextValue(rVal reflect.Value, cv contextValue) error {
+	if !rVal.CanSet() {
+		return ErrCantSetValue
+	}
+	v := reflect.ValueOf(cv.v)
+	if v.Type().AssignableTo(rVal.Type()) {
+		rVal.Set(v)
+		return nil
+	}
+	switch cvv := cv.v.(type) {
+	case []string:
+		return setReflectValue(rVal, cvv)
+	case string:
+		return setReflectValue(rVal, []string{cvv})
+	case fmt.Stringer:
+		return setReflectValue(rVal, []string{cvv.String()})
+	}
+	return setReflectValue(rVal, []string{fmt.Sprint(cv.v)})
+}
@@ -0,0 +1,137 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/yikailee/golang/dto"
+)
+
+type sourceUser struct {
+	ID       int64  `dto:"id,path"`
+	Tenant   string `dto:"X-Tenant,header" required:"false"`
+	Session  string `dto:"session,cookie" required:"false"`
+	UserID   int64  `dto:"userID,context" required:"false"`
+	Name     string `dto:"name" required:"false"`
+	setItems map[string]bool
+}
+
+func (u *sourceUser) AlreadySet(dtoName string) bool {
+	_, ok := u.setItems[dtoName]
+	return ok
+}
+
+func (u *sourceUser) MarkSet(dtoName string) {
+	if u.setItems == nil {
+		u.setItems = make(map[string]bool)
+	}
+	u.setItems[dtoName] = true
+}
+
+func (u *sourceUser) MarkAllUnset() {
+	u.setItems = make(map[string]bool)
+}
+
+func newSourceRequest(url string, form url.Values) *http.Request {
+	r, _ := http.NewRequest("POST", url, bytes.NewBufferString(form.Encode()))
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Add("X-Tenant", "acme")
+	r.AddCookie(&http.Cookie{Name: "session", Value: "s3cr3t"})
+	r = r.WithContext(context.WithValue(r.Context(), ContextKey("userID"), int64(42)))
+	return mux.SetURLVars(r, map[string]string{"id": "7", "name": "path-name"})
+}
+
+func TestParamSourcesHappyPath(t *testing.T) {
+	testData := []struct {
+		name   string
+		url    string
+		form   url.Values
+		wanted sourceUser
+	}{
+		{
+			name: "explicit sources",
+			url:  "http://localhost:8080/users/7?id=8",
+			wanted: sourceUser{
+				ID:      7,
+				Tenant:  "acme",
+				Session: "s3cr3t",
+				UserID:  42,
+				Name:    "path-name",
+			},
+		},
+		{
+			name: "path takes precedence over query and form",
+			url:  "http://localhost:8080/users/7?name=query-name",
+			form: url.Values{"name": []string{"form-name"}},
+			wanted: sourceUser{
+				ID:      7,
+				Tenant:  "acme",
+				Session: "s3cr3t",
+				UserID:  42,
+				Name:    "path-name",
+			},
+		},
+	}
+
+	for _, test := range testData {
+		r := newSourceRequest(test.url, test.form)
+		u := &sourceUser{}
+		err := BindRequestParams(r, u)
+		assert.Nil(t, err, test.name)
+		u.setItems = nil
+		assert.Equal(t, test.wanted, *u, test.name)
+	}
+}
+
+func TestParamSourcesPrecedence(t *testing.T) {
+	r, _ := http.NewRequest("POST", "http://localhost:8080/users?age=28&hobby=query", bytes.NewBufferString("hobby=form"))
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	u := &user1{}
+	err := BindRequestParams(r, u)
+	assert.Nil(t, err, "query over form")
+	assert.Equal(t, []string{"query"}, u.Hobby, "query over form")
+
+	r, _ = http.NewRequest("POST", "http://localhost:8080/users?age=28&hobby=query", bytes.NewBufferString("hobby=form"))
+	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	err = BindRequestParams(r, &user1{}, RejectAmbiguousParams())
+	assert.True(t, errors.Is(err, ErrAmbiguousParam), "reject ambiguous")
+}
+
+func TestParamSourcesError(t *testing.T) {
+	testData := []struct {
+		name   string
+		url    string
+		in     dto.ValidRequestDTO
+		wanted error
+	}{
+		{
+			name:   "path param only in query",
+			url:    "http://localhost:8080/users?id=8",
+			in:     &sourceUser{},
+			wanted: ErrMissingParam,
+		},
+		{
+			name:   "unknown source",
+			url:    "http://localhost:8080/users",
+			in:     &unknownSourceUser{},
+			wanted: ErrUnknownSource,
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("GET", test.url, nil)
+		err := BindRequestParams(r, test.in)
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+	}
+}
+
+type unknownSourceUser struct {
+	Extra string `dto:"extra,body-param"`
+	sourceUser
+}
@@ -0,0 +1,189 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/yikailee/golang/dto"
+)
+
+// decodeJSONObject decodes a JSON object keeping numbers as json.Number so
+// large integers do not lose precision. An empty body decodes to nil.
+func decodeJSONObject(r io.Reader) (map[string]interface{}, error) {
+	content := json.NewDecoder(r)
+	content.UseNumber()
+	var v interface{}
+	if err := content.Decode(&v); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		if isBodyTooLarge(err) {
+			return nil, ErrBodyTooLarge
+		}
+		return nil, ErrIllegalJSON
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, ErrIllegalJSON
+	}
+	return obj, nil
+}
+
+// setJSONValue sets rVal from a value decoded by decodeJSONObject. Objects
+// are bound into structs following their dto tags, null resets rVal to its
+// zero value.
+func setJSONValue(rVal reflect.Value, jv interface{}) error {
+	if !rVal.CanSet() {
+		return ErrCantSetValue
+	}
+	if jv == nil {
+		rVal.Set(reflect.Zero(rVal.Type()))
+		return nil
+	}
+
+	switch rVal.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(rVal.Type().Elem())
+		if err := setJSONValue(elem.Elem(), jv); err != nil {
+			return err
+		}
+		rVal.Set(elem)
+		return nil
+	case reflect.Struct:
+		obj, ok := jv.(map[string]interface{})
+		if !ok {
+			return ErrErrorType
+		}
+		plan, err := getBindPlan(rVal.Type())
+		if err != nil {
+			return err
+		}
+		_, err = plan.bind(rVal, &paramSources{}, obj, discardMarks{})
+		return err
+	case reflect.Map:
+		obj, ok := jv.(map[string]interface{})
+		if !ok || rVal.Type().Key().Kind() != reflect.String {
+			return ErrErrorType
+		}
+		t := rVal.Type()
+		m := reflect.MakeMapWithSize(t, len(obj))
+		for k, v := range obj {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := setJSONValue(elem, v); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			m.SetMapIndex(reflect.ValueOf(k).Convert(t.Key()), elem)
+		}
+		rVal.Set(m)
+		return nil
+	case reflect.Array, reflect.Slice:
+		arr, ok := jv.([]interface{})
+		if !ok {
+			return ErrErrorType
+		}
+		n := len(arr)
+		if rVal.Kind() == reflect.Array {
+			if rVal.Len() > n {
+				return NotEnoughValues(rVal.Len(), n)
+			}
+			n = rVal.Len()
+		} else {
+			rVal.Set(reflect.MakeSlice(rVal.Type(), n, n))
+		}
+		for i := 0; i < n; i++ {
+			if err := setJSONValue(rVal.Index(i), arr[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch v := jv.(type) {
+	case string:
+		return setReflectSingleValue(rVal, v)
+	case json.Number:
+		if rVal.Kind() == reflect.Bool {
+			return ErrErrorType
+		}
+		return setReflectSingleValue(rVal, v.String())
+	case bool:
+		if rVal.Kind() != reflect.Bool && rVal.Kind() != reflect.String {
+			return ErrErrorType
+		}
+		return setReflectSingleValue(rVal, strconv.FormatBool(v))
+	}
+	return ErrErrorType
+}
+
+// checkUnknownJSONFields reports the keys of body, or of its nested objects,
+// that do not match a dto tag of the plan.
+func checkUnknownJSONFields(plan *bindPlan, body map[string]interface{}, prefix string) error {
+	unknown, err := unknownJSONFields(plan, body, prefix)
+	if err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownField, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func unknownJSONFields(plan *bindPlan, body map[string]interface{}, prefix string) (unknown []string, err error) {
+	for k, jv := range body {
+		fp, ok := plan.bodyFields[k]
+		if !ok {
+			unknown = append(unknown, prefix+k)
+			continue
+		}
+		if fp.nested != nil {
+			if obj, ok := jv.(map[string]interface{}); ok {
+				names, err := unknownJSONFields(fp.nested, obj, prefix+k+".")
+				if err != nil {
+					return nil, err
+				}
+				unknown = append(unknown, names...)
+			}
+			continue
+		}
+
+		ft := fp.typ
+		for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			ft = ft.Elem()
+		}
+		if ft.Kind() != reflect.Struct {
+			continue
+		}
+		elemPlan, err := getBindPlan(ft)
+		if err != nil {
+			return nil, err
+		}
+		objs := []interface{}{jv}
+		if arr, ok := jv.([]interface{}); ok {
+			objs = arr
+		}
+		for _, o := range objs {
+			if obj, ok := o.(map[string]interface{}); ok {
+				names, err := unknownJSONFields(elemPlan, obj, prefix+k+".")
+				if err != nil {
+					return nil, err
+				}
+				unknown = append(unknown, names...)
+			}
+		}
+	}
+	return sortedUnique(unknown), nil
+}
+
+// discardMarks is used to bind structs nested in JSON arrays, whose fields
+// are not tracked by the request DTO.
+type discardMarks struct{}
+
+func (discardMarks) AlreadySet(dtoName string) bool { return false }
+func (discardMarks) MarkSet(dtoName string)         {}
+func (discardMarks) MarkAllUnset()                  {}
+
+var _ dto.ValidRequestDTO = discardMarks{}
@@ -0,0 +1,201 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeJSONObjectHappyPath(t *testing.T) {
+	testData := []struct {
+		name   string
+		input  string
+		wanted map[string]interface{}
+	}{
+		{
+			name:   "empty body",
+			input:  ``,
+			wanted: nil,
+		},
+		{
+			name:   "empty object",
+			input:  `{}`,
+			wanted: map[string]interface{}{},
+		},
+		{
+			name:  "typed values",
+			input: `{"key1": "value1", "key2": ["value2"], "key3": 3, "key4": true, "key5": null, "key6": {"skey1": "v1"}}`,
+			wanted: map[string]interface{}{
+				"key1": "value1",
+				"key2": []interface{}{"value2"},
+				"key3": json.Number("3"),
+				"key4": true,
+				"key5": nil,
+				"key6": map[string]interface{}{"skey1": "v1"},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		res, err := decodeJSONObject(strings.NewReader(test.input))
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wanted, res, test.name)
+	}
+}
+
+func TestDecodeJSONObjectError(t *testing.T) {
+	testData := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "invalid json format",
+			input: `{"key1", "value", "key2": "value2}`,
+		},
+		{
+			name:  "not an object",
+			input: `["value1", "value2"]`,
+		},
+		{
+			name:  "unterminated nested object",
+			input: `{"key1":"value1", "key2": {"skey1":"v1"`,
+		},
+	}
+
+	for _, test := range testData {
+		_, err := decodeJSONObject(strings.NewReader(test.input))
+		assert.Equal(t, ErrIllegalJSON, err, test.name)
+	}
+}
+
+type jsonItem struct {
+	Name  string `dto:"name"`
+	Count int64  `dto:"count" required:"false"`
+}
+
+type jsonUser struct {
+	ID       int64      `dto:"id"`
+	Score    float64    `dto:"score" required:"false"`
+	Active   bool       `dto:"active" required:"false"`
+	Nickname string     `dto:"nickname" required:"false"`
+	Items    []jsonItem `dto:"items" required:"false"`
+	Owner    *jsonItem  `dto:"owner" required:"false"`
+	setItems map[string]bool
+}
+
+func (u *jsonUser) AlreadySet(dtoName string) bool {
+	_, ok := u.setItems[dtoName]
+	return ok
+}
+
+func (u *jsonUser) MarkSet(dtoName string) {
+	if u.setItems == nil {
+		u.setItems = make(map[string]bool)
+	}
+	u.setItems[dtoName] = true
+}
+
+func (u *jsonUser) MarkAllUnset() {
+	u.setItems = make(map[string]bool)
+}
+
+func TestBindJSONTypedValuesHappyPath(t *testing.T) {
+	testData := []struct {
+		name     string
+		jsonBody string
+		wanted   *jsonUser
+	}{
+		{
+			name:     "numbers and booleans",
+			jsonBody: `{"id": 9007199254740993, "score": 3.5, "active": true}`,
+			wanted: &jsonUser{
+				ID:       9007199254740993,
+				Score:    3.5,
+				Active:   true,
+				setItems: map[string]bool{"id": true, "score": true, "active": true},
+			},
+		},
+		{
+			name:     "null resets the field",
+			jsonBody: `{"id": 1, "nickname": null}`,
+			wanted: &jsonUser{
+				ID:       1,
+				setItems: map[string]bool{"id": true, "nickname": true},
+			},
+		},
+		{
+			name:     "arrays of objects and nested objects",
+			jsonBody: `{"id": 1, "items": [{"name": "a", "count": 2}, {"name": "b"}], "owner": {"name": "c"}}`,
+			wanted: &jsonUser{
+				ID:       1,
+				Items:    []jsonItem{{Name: "a", Count: 2}, {Name: "b"}},
+				Owner:    &jsonItem{Name: "c"},
+				setItems: map[string]bool{"id": true, "items": true, "owner": true, "owner.name": true},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("POST", "http://localhost:8080/users", bytes.NewBufferString(test.jsonBody))
+		r.Header.Add("Content-Type", "application/json")
+		u := &jsonUser{Nickname: "old"}
+		err := BindRequestParams(r, u)
+		assert.Nil(t, err, test.name)
+		u.Nickname, test.wanted.Nickname = "", ""
+		assert.Equal(t, test.wanted, u, test.name)
+	}
+}
+
+func TestBindJSONTypedValuesError(t *testing.T) {
+	testData := []struct {
+		name     string
+		jsonBody string
+		opts     []Option
+		wanted   error
+	}{
+		{
+			name:     "missing required field",
+			jsonBody: `{"score": 3.5}`,
+			wanted:   ErrMissingParam,
+		},
+		{
+			name:     "boolean into int",
+			jsonBody: `{"id": true}`,
+			wanted:   ErrErrorType,
+		},
+		{
+			name:     "array into int",
+			jsonBody: `{"id": [1]}`,
+			wanted:   ErrErrorType,
+		},
+		{
+			name:     "illegal json",
+			jsonBody: `{"id": 1`,
+			wanted:   ErrIllegalJSON,
+		},
+		{
+			name:     "unknown field",
+			jsonBody: `{"id": 1, "nick": "bob"}`,
+			opts:     []Option{DisallowUnknownFields()},
+			wanted:   ErrUnknownField,
+		},
+		{
+			name:     "unknown nested field",
+			jsonBody: `{"id": 1, "items": [{"name": "a", "size": 2}]}`,
+			opts:     []Option{DisallowUnknownFields()},
+			wanted:   ErrUnknownField,
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("POST", "http://localhost:8080/users", bytes.NewBufferString(test.jsonBody))
+		r.Header.Add("Content-Type", "application/json")
+		err := BindRequestParams(r, &jsonUser{}, test.opts...)
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+	}
+}
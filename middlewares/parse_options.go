@@ -0,0 +1,64 @@
+package middlewares
+
+// Option customizes how BindRequestParams binds a request.
+type Option func(*parseOptions)
+
+type parseOptions struct {
+	disallowUnknownFields bool
+	maxBodyBytes          int64
+	maxMultipartBytes     int64
+	maxMemory             int64
+	rejectAmbiguous       bool
+	strict                bool
+}
+
+func newParseOptions(opts []Option) *parseOptions {
+	o := &parseOptions{maxMemory: defaultMaxMemory}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// DisallowUnknownFields makes binding fail when the JSON body contains keys
+// that do not match any dto tag.
+func DisallowUnknownFields() Option {
+	return func(o *parseOptions) {
+		o.disallowUnknownFields = true
+	}
+}
+
+// MaxBodyBytes limits the size of the request body. The limit set for the
+// route by LimitBody still applies, so it can only lower it. Larger bodies
+// make binding fail with ErrBodyTooLarge.
+func MaxBodyBytes(n int64) Option {
+	return func(o *parseOptions) {
+		o.maxBodyBytes = n
+	}
+}
+
+// MaxMultipartBytes limits the size of multipart bodies instead of
+// MaxBodyBytes, it can only lower the limit set for the route by LimitBody.
+// Without any of them multipart bodies are limited to 32 MB, raise it for
+// larger uploads.
+func MaxMultipartBytes(n int64) Option {
+	return func(o *parseOptions) {
+		o.maxMultipartBytes = n
+	}
+}
+
+// MaxMemory sets how many bytes of a multipart body are kept in memory, the
+// remaining file parts are stored in temporary files.
+func MaxMemory(n int64) Option {
+	return func(o *parseOptions) {
+		o.maxMemory = n
+	}
+}
+
+// RejectAmbiguousParams makes binding fail with ErrAmbiguousParam when the
+// name of a field without an explicit source is found in several sources.
+func RejectAmbiguousParams() Option {
+	return func(o *parseOptions) {
+		o.rejectAmbiguous = true
+	}
+}
@@ -1,13 +1,12 @@
 package middlewares
 
 import (
-	"encoding/json"
 	"errors"
-	"io"
 	"mime"
 	"net/http"
 	"reflect"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/yikailee/golang/dto"
@@ -23,183 +22,218 @@ var (
 	ErrInvalidReflectVal = errors.New("invalid reflect value")
 	ErrErrorType         = errors.New("error type")
 	ErrIllegalJSON       = errors.New("ilegal json format")
+	ErrMissingParam      = errors.New("missing required param")
+	ErrUnknownField      = errors.New("unknown field")
+	ErrBodyTooLarge      = errors.New("request body too large")
+	ErrAmbiguousParam    = errors.New("ambiguous param")
+	ErrUnknownSource     = errors.New("unknown param source")
 )
 
 const (
-	defaultMaxMemory = 32 << 20 // 32 MB
+	defaultMaxMemory         = 32 << 20 // 32 MB
+	defaultMaxBodyBytes      = 10 << 20 // 10 MB is a lot of text.
+	defaultMaxMultipartBytes = 32 << 20 // uploads need more room.
 )
 
-func appendValues(dst, src map[string][]string) {
-	for k, vs := range src {
-		dst[k] = append(dst[k], vs...)
+func parseUrlEmbededParams(r *http.Request) (values map[string][]string, err error) {
+	// check whether params if embeded in url
+	values = make(map[string][]string)
+	vars := mux.Vars(r)
+	if nil != vars {
+		for k, v := range vars {
+			values[k] = append(values[k], v)
+		}
 	}
+	return
 }
 
-func getValuesFromJSON(r io.Reader) (values map[string][]string, err error) {
-	content := json.NewDecoder(r)
-	values = make(map[string][]string)
-	for {
-		k, e := content.Token()
-		if e == io.EOF {
-			break
-		}
-		if e != nil {
-			err = ErrIllegalJSON
-			break
-		}
+// normalizeKeys rewrites bracket keys ("filter[name]") to the dotted form
+// ("filter.name") used to look up nested struct fields.
+func normalizeKeys(values map[string][]string) map[string][]string {
+	normalized := make(map[string][]string, len(values))
+	for k, vs := range values {
+		k = normalizeKey(k)
+		normalized[k] = append(normalized[k], vs...)
+	}
+	return normalized
+}
 
-		kn := reflect.TypeOf(k).Name()
-		if kn == "Delim" {
-			continue
-		}
-		if kn != "string" {
-			err = ErrIllegalJSON
-			break
-		}
-		kv := reflect.ValueOf(k).String()
+func normalizeKey(k string) string {
+	if strings.IndexByte(k, '[') >= 0 {
+		k = strings.Replace(k, "]", "", -1)
+		k = strings.Replace(k, "[", ".", -1)
+	}
+	return k
+}
 
-		v, e := content.Token()
-		if e != nil {
-			err = ErrIllegalJSON
-			break
+func hasValuesWithPrefix(values map[string][]string, prefix string) bool {
+	for k := range values {
+		if strings.HasPrefix(k, prefix) {
+			return true
 		}
-		vn := reflect.TypeOf(v).Name()
-		if vn != "Delim" {
-			if vn != "string" {
-				err = ErrIllegalJSON
-				break
-			}
+	}
+	return false
+}
 
-			vv := reflect.ValueOf(v).String()
-			values[kv] = append(values[kv], vv)
-			continue
+func nestedStructType(t reflect.Type) bool {
+	if isFileType(t) {
+		return false
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
 
-		} else if reflect.ValueOf(v).Int() != 91 { // '[' == 91
-			err = ErrIllegalJSON
-			break
-		}
+// ParseRequestParams binds the request params into in and reports whether
+// all required params could be bound. Use BindRequestParams to get the
+// reason of a failure.
+func ParseRequestParams(r *http.Request, in dto.ValidRequestDTO, opts ...Option) bool {
+	return BindRequestParams(r, in, opts...) == nil
+}
 
-		for content.More() {
-			v, err := content.Token()
-			if err != nil || reflect.TypeOf(v).Name() != "string" {
-				err = ErrIllegalJSON
-				break
-			}
-			vv := reflect.ValueOf(v).String()
-			values[kv] = append(values[kv], vv)
-		}
-		if err != nil {
-			break
-		}
+// Binder is implemented by DTOs with a Bind method generated by cmd/dtogen,
+// which BindRequestParams uses instead of reflection.
+type Binder interface {
+	Bind(r *http.Request, opts ...Option) error
+}
+
+// BindRequestParams binds the params of r into the dto tagged fields of in.
+// Fields bind from the source named in their dto tag, or else from the first
+// of the mux vars, query, form and JSON body that has their dto name.
+func BindRequestParams(r *http.Request, in dto.ValidRequestDTO, opts ...Option) error {
+	if b, ok := in.(Binder); ok {
+		return b.Bind(r, opts...)
 	}
+	return bindRequestParams(r, reflect.ValueOf(in), in, opts)
+}
+
+// bindRequestParams binds r into the struct v, or the struct v points to,
+// marking the set fields in in.
+func bindRequestParams(r *http.Request, v reflect.Value, in dto.ValidRequestDTO, opts []Option) error {
+	o := newParseOptions(opts)
+	body, supported, err := parseRequestBody(r, o)
 	if err != nil {
-		values = make(map[string][]string)
+		return err
 	}
-	return
-}
+	src := newParamSources(r, o)
 
-func parseJSONBody(r *http.Request) (err error) {
-	if r.Method != "POST" && r.Method != "PUT" && r.Method != "PATCH" {
-		return
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
 	}
-	if r.Body == nil {
-		err = errors.New("missing request json body")
-		return
+	if v.Kind() != reflect.Struct {
+		return ErrUnhandleType
 	}
-	ct := r.Header.Get("Content-Type")
-	if ct == "" {
-		ct = "application/octet-stream"
+	plan, err := getBindPlan(v.Type())
+	if err != nil {
+		return err
 	}
-	ct, _, err = mime.ParseMediaType(ct)
-	switch {
-	case ct == "application/json":
-		var reader io.Reader = r.Body
-		maxContentSize := int64(10 << 20) // 10 MB is a lot of text.
-		reader = io.LimitReader(r.Body, maxContentSize+1)
-		vs, e := getValuesFromJSON(reader)
-		if err == nil {
-			err = e
+	if !supported && plan.requiresBody {
+		return ErrUnsupportedMediaType
+	}
+	if o.strict || plan.strict {
+		if err := checkStrictParams(plan, src, body); err != nil {
+			return err
 		}
-		if err == nil {
-			appendValues(r.Form, vs)
+	}
+	if body != nil && o.disallowUnknownFields {
+		if err := checkUnknownJSONFields(plan, body, ""); err != nil {
+			return err
 		}
 	}
-
-	return
+	_, err = plan.bind(v, src, body, in)
+	return err
 }
 
-func parseUrlEmbededParams(r *http.Request) (values map[string][]string, err error) {
-	// check whether params if embeded in url
-	values = make(map[string][]string)
-	vars := mux.Vars(r)
-	if nil != vars {
-		for k, v := range vars {
-			values[k] = append(values[k], v)
-		}
+// parseRequestBody parses the form and multipart values into r and returns
+// the decoded body, if any. supported is false when no BodyDecoder handles
+// the media type of the body.
+func parseRequestBody(r *http.Request, o *parseOptions) (body map[string]interface{}, supported bool, err error) {
+	if err := limitRequestBody(r, o); err != nil {
+		return nil, false, err
 	}
-	return
+	// ParseMultipartForm drops the ParseForm error of non multipart bodies
+	if err := r.ParseForm(); isBodyTooLarge(err) {
+		return nil, false, ErrBodyTooLarge
+	}
+	if err := r.ParseMultipartForm(o.maxMemory); isBodyTooLarge(err) {
+		return nil, false, ErrBodyTooLarge
+	}
+	trackMultipartForm(r)
+	return parseBody(r, o)
 }
 
-func ParseRequestParams(r *http.Request, in dto.ValidRequestDTO) bool {
-	values, _ := parseUrlEmbededParams(r)
-	r.ParseMultipartForm(defaultMaxMemory)
-	parseJSONBody(r)
-	appendValues(values, r.Form)
-
-	v := reflect.ValueOf(in)
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+// StatusCode returns the HTTP status code a handler should answer with when
+// BindRequestParams fails with err.
+func StatusCode(err error) int {
+	switch {
+	case err == nil:
+		return http.StatusOK
+	case errors.Is(err, ErrBodyTooLarge), errors.Is(err, ErrFileTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrFileType), errors.Is(err, ErrUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
 	}
-	t := v.Type()
-	for i := 0; i < v.NumField(); i++ {
-		wanted := t.Field(i).Tag.Get("dto")
-		if wanted == "" {
-			continue
-		}
-		required := !(t.Field(i).Tag.Get("required") == "false")
-		if _, ok := values[wanted]; !ok {
-			if required {
-				return false
-			}
-			continue
-		}
+}
 
-		err := setReflectValue(v.Field(i), values[wanted])
-		if err != nil {
-			if required {
-				return false
-			}
-			continue
+// limitRequestBody wraps the body of r in a http.MaxBytesReader using the
+// MaxMultipartBytes option for multipart bodies, the MaxBodyBytes option,
+// the LimitBody route limit, or else defaultMaxMultipartBytes for multipart
+// bodies and defaultMaxBodyBytes for the others.
+func limitRequestBody(r *http.Request, o *parseOptions) error {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil
+	}
+	multipart := isMultipart(r)
+	limit := o.maxBodyBytes
+	if multipart && o.maxMultipartBytes > 0 {
+		limit = o.maxMultipartBytes
+	}
+	if limit <= 0 {
+		if n, ok := bodyLimitFromContext(r.Context()); ok {
+			limit = n
+		} else if multipart {
+			limit = defaultMaxMultipartBytes
+		} else {
+			limit = defaultMaxBodyBytes
 		}
-		in.MarkSet(wanted)
 	}
+	if r.ContentLength > limit {
+		return ErrBodyTooLarge
+	}
+	r.Body = http.MaxBytesReader(nil, r.Body, limit)
+	return nil
+}
 
-	return true
+func isMultipart(r *http.Request) bool {
+	ct, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && ct == "multipart/form-data"
 }
 
-func setReflectSingleValue(rVal reflect.Value, str string) error {
-	if !rVal.CanSet() {
-		return ErrCantSetValue
-	}
-	if !rVal.IsValid() {
-		return ErrInvalidReflectVal
-	}
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
 
-	// handle int type
-	intType := 0
-	switch rVal.Kind() {
-	case reflect.Int8:
-		intType = 8
-	case reflect.Int16:
-		intType = 16
-	case reflect.Int32:
-	case reflect.Int:
-		intType = 32
-	case reflect.Int64:
-		intType = 64
-	}
-	if 0 != intType {
-		p, err := strconv.ParseInt(str, 10, intType)
+// singleSetter converts str and sets it into rVal, which has been checked to
+// be valid and settable.
+type singleSetter func(rVal reflect.Value, str string) error
+
+var (
+	setInt8Value    = intSetter(8)
+	setInt16Value   = intSetter(16)
+	setInt32Value   = intSetter(32)
+	setInt64Value   = intSetter(64)
+	setIntValue     = intSetter(0)
+	setFloat32Value = floatSetter(32)
+	setFloat64Value = floatSetter(64)
+)
+
+func intSetter(bitSize int) singleSetter {
+	return func(rVal reflect.Value, str string) error {
+		p, err := strconv.ParseInt(str, 10, bitSize)
 		if err != nil {
 			return err
 		}
@@ -209,17 +243,11 @@ func setReflectSingleValue(rVal reflect.Value, str string) error {
 		rVal.SetInt(p)
 		return nil
 	}
+}
 
-	// handle float type
-	fType := 0
-	switch rVal.Kind() {
-	case reflect.Float32:
-		fType = 32
-	case reflect.Float64:
-		fType = 64
-	}
-	if 0 != fType {
-		p, err := strconv.ParseFloat(str, fType)
+func floatSetter(bitSize int) singleSetter {
+	return func(rVal reflect.Value, str string) error {
+		p, err := strconv.ParseFloat(str, bitSize)
 		if err != nil {
 			return err
 		}
@@ -229,54 +257,127 @@ func setReflectSingleValue(rVal reflect.Value, str string) error {
 		rVal.SetFloat(p)
 		return nil
 	}
+}
 
-	// handle other type
-	switch rVal.Kind() {
-	case reflect.Bool:
-		p, err := strconv.ParseBool(str)
-		if err != nil {
-			return err
-		}
-		rVal.SetBool(p)
-	case reflect.String:
-		rVal.SetString(str)
-	default:
-		return ErrUnhandleType
+func setBoolValue(rVal reflect.Value, str string) error {
+	p, err := strconv.ParseBool(str)
+	if err != nil {
+		return err
 	}
+	rVal.SetBool(p)
+	return nil
+}
 
+func setStringValue(rVal reflect.Value, str string) error {
+	rVal.SetString(str)
 	return nil
 }
 
-func setReflectValue(rVal reflect.Value, strs []string) error {
-	if len(strs) == 0 {
-		return ErrEmptyValue
+func setUnhandledValue(rVal reflect.Value, str string) error {
+	return ErrUnhandleType
+}
+
+// newSingleSetter returns the converter for values of kind k, so binding
+// plans choose it once per field instead of once per request.
+func newSingleSetter(k reflect.Kind) singleSetter {
+	switch k {
+	case reflect.Int8:
+		return setInt8Value
+	case reflect.Int16:
+		return setInt16Value
+	case reflect.Int32:
+		return setInt32Value
+	case reflect.Int:
+		return setIntValue
+	case reflect.Int64:
+		return setInt64Value
+	case reflect.Float32:
+		return setFloat32Value
+	case reflect.Float64:
+		return setFloat64Value
+	case reflect.Bool:
+		return setBoolValue
+	case reflect.String:
+		return setStringValue
 	}
+	return setUnhandledValue
+}
 
-	switch rVal.Kind() {
+// valuesSetter sets rVal from all the values of a param.
+type valuesSetter func(rVal reflect.Value, strs []string) error
+
+// newValuesSetter returns the converter for fields of type t: arrays and
+// slices take one value per element, other types the first value.
+func newValuesSetter(t reflect.Type) valuesSetter {
+	switch t.Kind() {
 	case reflect.Array:
-		var n = rVal.Len()
-		if n > len(strs) {
-			return ErrNotEnoughValue
-		}
-		for i := 0; i < n; i++ {
-			err := setReflectSingleValue(rVal.Index(i), strs[i])
-			if err != nil {
+		elem := newSingleSetter(t.Elem().Kind())
+		return func(rVal reflect.Value, strs []string) error {
+			if err := checkValues(rVal, strs); err != nil {
 				return err
 			}
+			var n = rVal.Len()
+			if n > len(strs) {
+				return NotEnoughValues(n, len(strs))
+			}
+			for i := 0; i < n; i++ {
+				if err := elem(rVal.Index(i), strs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
 		}
 	case reflect.Slice:
-		t := rVal.Type()
-		n := len(strs)
-		rVal.Set(reflect.MakeSlice(t, n, n))
-		for i := 0; i < n; i++ {
-			err := setReflectSingleValue(rVal.Index(i), strs[i])
-			if err != nil {
+		elem := newSingleSetter(t.Elem().Kind())
+		return func(rVal reflect.Value, strs []string) error {
+			if err := checkValues(rVal, strs); err != nil {
 				return err
 			}
+			n := len(strs)
+			rVal.Set(reflect.MakeSlice(t, n, n))
+			for i := 0; i < n; i++ {
+				if err := elem(rVal.Index(i), strs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
 		}
-	default:
-		return setReflectSingleValue(rVal, strs[0])
 	}
+	single := newSingleSetter(t.Kind())
+	return func(rVal reflect.Value, strs []string) error {
+		if err := checkValues(rVal, strs); err != nil {
+			return err
+		}
+		return single(rVal, strs[0])
+	}
+}
 
+func checkValues(rVal reflect.Value, strs []string) error {
+	if len(strs) == 0 {
+		return ErrEmptyValue
+	}
+	if !rVal.CanSet() {
+		return ErrCantSetValue
+	}
 	return nil
 }
+
+func setReflectSingleValue(rVal reflect.Value, str string) error {
+	if !rVal.CanSet() {
+		return ErrCantSetValue
+	}
+	if !rVal.IsValid() {
+		return ErrInvalidReflectVal
+	}
+	return newSingleSetter(rVal.Kind())(rVal, str)
+}
+
+func setReflectValue(rVal reflect.Value, strs []string) error {
+	if len(strs) == 0 {
+		return ErrEmptyValue
+	}
+	if !rVal.IsValid() {
+		return ErrInvalidReflectVal
+	}
+	return newValuesSetter(rVal.Type())(rVal, strs)
+}
@@ -313,63 +313,6 @@ func TestSliceSetReflectValueError(t *testing.T) {
 	}
 }
 
-func TestGetJsonValuesHappyPath(t *testing.T) {
-	testData := []struct {
-		name   string
-		input  string
-		wanted map[string][]string
-	}{
-		{
-			name:   "happy path 1",
-			input:  `{}`,
-			wanted: map[string][]string{},
-		},
-		{
-			name:   "happy path 2",
-			input:  `{"key1": "value1","key2": ["value2", "value3", "value4"]}`,
-			wanted: map[string][]string{"key1": []string{"value1"}, "key2": []string{"value2", "value3", "value4"}},
-		},
-	}
-
-	for _, test := range testData {
-		r := strings.NewReader(test.input)
-		res, err := getValuesFromJSON(r)
-
-		assert.Nil(t, err, test.name)
-
-		eq := reflect.DeepEqual(res, test.wanted)
-
-		assert.Equal(t, true, eq, test.name)
-	}
-}
-
-func TestGetJsonValuesError(t *testing.T) {
-	testData := []struct {
-		name  string
-		input string
-	}{
-		{
-			name:  "invalid json format 2",
-			input: `{"key1", "value", "key2": "value2}`,
-		},
-		{
-			name:  "invalid data (not string)",
-			input: `{"key": 1}`,
-		},
-		{
-			name:  "too complex format",
-			input: `{"key1":"value1", "key2": {"skey1":"v1", "skey2":"v2"}}`,
-		},
-	}
-
-	for _, test := range testData {
-		r := strings.NewReader(test.input)
-		_, err := getValuesFromJSON(r)
-
-		assert.NotNil(t, err, test.name)
-	}
-}
-
 type user1 struct {
 	Name     string   `dto:"name" required:"false"`
 	Age      int      `dto:"age" rquired:"true"`
@@ -393,6 +336,42 @@ func (u *user1) MarkAllUnset() {
 	u.setItems = make(map[string]bool)
 }
 
+//go:generate go run ../cmd/dtogen -tests -type genUser1
+
+// genUser1 has the dto tags of user1 and a Bind method generated by
+// cmd/dtogen.
+type genUser1 struct {
+	Name     string   `dto:"name" required:"false"`
+	Age      int      `dto:"age" rquired:"true"`
+	Hobby    []string `dto:"hobby" required:"false"`
+	setItems map[string]bool
+}
+
+// user1Binders bind a request into a user1 with ParseRequestParams and with
+// the generated Bind method of genUser1, so that the test tables check that
+// both behave the same.
+var user1Binders = []struct {
+	name string
+	bind func(r *http.Request) (*user1, bool)
+}{
+	{
+		name: "reflect",
+		bind: func(r *http.Request) (*user1, bool) {
+			u := &user1{}
+			ok := ParseRequestParams(r, u)
+			return u, ok
+		},
+	},
+	{
+		name: "generated",
+		bind: func(r *http.Request) (*user1, bool) {
+			u := &genUser1{}
+			ok := u.Bind(r) == nil
+			return &user1{Name: u.Name, Age: u.Age, Hobby: u.Hobby, setItems: u.setItems}, ok
+		},
+	},
+}
+
 func TestParseGETRequestParamsHappyPath(t *testing.T) {
 	testData := []struct {
 		name   string
@@ -409,16 +388,26 @@ func TestParseGETRequestParamsHappyPath(t *testing.T) {
 				setItems: map[string]bool{"age": true, "hobby": true},
 			},
 		},
+		{
+			name: "int wider than 32 bits",
+			url:  "http://localhost:8080/users?age=3000000000",
+			wanted: &user1{
+				Age:      3000000000,
+				setItems: map[string]bool{"age": true},
+			},
+		},
 	}
 
 	for _, test := range testData {
-		r, _ := http.NewRequest("GET", test.url, strings.NewReader(""))
-		u := &user1{}
-		ok := ParseRequestParams(r, u)
-		assert.True(t, ok, test.name)
-		assert.Equal(t, test.wanted.Age, u.Age, test.name)
-		assert.Equal(t, test.wanted.Hobby, u.Hobby, test.name)
-		assert.Equal(t, test.wanted.setItems, u.setItems, test.name)
+		for _, binder := range user1Binders {
+			name := test.name + " (" + binder.name + ")"
+			r, _ := http.NewRequest("GET", test.url, strings.NewReader(""))
+			u, ok := binder.bind(r)
+			assert.True(t, ok, name)
+			assert.Equal(t, test.wanted.Age, u.Age, name)
+			assert.Equal(t, test.wanted.Hobby, u.Hobby, name)
+			assert.Equal(t, test.wanted.setItems, u.setItems, name)
+		}
 	}
 }
 
@@ -434,10 +423,12 @@ func TestParseGETRequestParamsError(t *testing.T) {
 	}
 
 	for _, test := range testData {
-		r, _ := http.NewRequest("GET", test.url, strings.NewReader(""))
-		u := &user1{}
-		ok := ParseRequestParams(r, u)
-		assert.False(t, ok, test.name)
+		for _, binder := range user1Binders {
+			name := test.name + " (" + binder.name + ")"
+			r, _ := http.NewRequest("GET", test.url, strings.NewReader(""))
+			_, ok := binder.bind(r)
+			assert.False(t, ok, name)
+		}
 	}
 }
 
@@ -472,14 +463,16 @@ func TestParsePostFormRequestParamsHappyPath(t *testing.T) {
 		},
 	}
 	for _, test := range testData {
-		r, _ := http.NewRequest("POST", test.url, bytes.NewBufferString(test.data.Encode()))
-		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-		u := &user1{}
-		ok := ParseRequestParams(r, u)
-		assert.True(t, ok, test.name)
-		assert.Equal(t, test.wanted.Age, u.Age, test.name)
-		assert.Equal(t, test.wanted.Hobby, u.Hobby, test.name)
-		assert.Equal(t, test.wanted.setItems, u.setItems, test.name)
+		for _, binder := range user1Binders {
+			name := test.name + " (" + binder.name + ")"
+			r, _ := http.NewRequest("POST", test.url, bytes.NewBufferString(test.data.Encode()))
+			r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			u, ok := binder.bind(r)
+			assert.True(t, ok, name)
+			assert.Equal(t, test.wanted.Age, u.Age, name)
+			assert.Equal(t, test.wanted.Hobby, u.Hobby, name)
+			assert.Equal(t, test.wanted.setItems, u.setItems, name)
+		}
 	}
 }
 
@@ -496,11 +489,13 @@ func TestParsePostFormRequestParamsError(t *testing.T) {
 		},
 	}
 	for _, test := range testData {
-		r, _ := http.NewRequest("POST", test.url, bytes.NewBufferString(test.data.Encode()))
-		r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
-		u := &user1{}
-		ok := ParseRequestParams(r, u)
-		assert.False(t, ok, test.name)
+		for _, binder := range user1Binders {
+			name := test.name + " (" + binder.name + ")"
+			r, _ := http.NewRequest("POST", test.url, bytes.NewBufferString(test.data.Encode()))
+			r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+			_, ok := binder.bind(r)
+			assert.False(t, ok, name)
+		}
 	}
 }
 
@@ -535,14 +530,16 @@ func TestParsePostJsonRequestParamsHappyPath(t *testing.T) {
 		},
 	}
 	for _, test := range testData {
-		r, _ := http.NewRequest("POST", test.url, bytes.NewBufferString(test.jsonBody))
-		r.Header.Add("Content-Type", "application/json")
-		u := &user1{}
-		ok := ParseRequestParams(r, u)
-		assert.True(t, ok, test.name)
-		assert.Equal(t, test.wanted.Age, u.Age, test.name)
-		assert.Equal(t, test.wanted.Hobby, u.Hobby, test.name)
-		assert.Equal(t, test.wanted.setItems, u.setItems, test.name)
+		for _, binder := range user1Binders {
+			name := test.name + " (" + binder.name + ")"
+			r, _ := http.NewRequest("POST", test.url, bytes.NewBufferString(test.jsonBody))
+			r.Header.Add("Content-Type", "application/json")
+			u, ok := binder.bind(r)
+			assert.True(t, ok, name)
+			assert.Equal(t, test.wanted.Age, u.Age, name)
+			assert.Equal(t, test.wanted.Hobby, u.Hobby, name)
+			assert.Equal(t, test.wanted.setItems, u.setItems, name)
+		}
 	}
 }
 
@@ -559,10 +556,148 @@ func TestParsePostJsonRequestParamsError(t *testing.T) {
 		},
 	}
 	for _, test := range testData {
-		r, _ := http.NewRequest("POST", test.url, bytes.NewBufferString(test.jsonBody))
-		r.Header.Add("Content-Type", "application/json")
-		u := &user1{}
+		for _, binder := range user1Binders {
+			name := test.name + " (" + binder.name + ")"
+			r, _ := http.NewRequest("POST", test.url, bytes.NewBufferString(test.jsonBody))
+			r.Header.Add("Content-Type", "application/json")
+			_, ok := binder.bind(r)
+			assert.False(t, ok, name)
+		}
+	}
+}
+
+type pagination struct {
+	Page int64 `dto:"page" required:"false"`
+	Size int64 `dto:"size" required:"false"`
+}
+
+type userFilter struct {
+	Name string `dto:"name"`
+	City string `dto:"city" required:"false"`
+}
+
+type user2 struct {
+	pagination
+	Filter   userFilter  `dto:"filter"`
+	Extra    *userFilter `dto:"extra" required:"false"`
+	setItems map[string]bool
+}
+
+func (u *user2) AlreadySet(dtoName string) bool {
+	_, ok := u.setItems[dtoName]
+	return ok
+}
+
+func (u *user2) MarkSet(dtoName string) {
+	if u.setItems == nil {
+		u.setItems = make(map[string]bool)
+	}
+	u.setItems[dtoName] = true
+}
+
+func (u *user2) MarkAllUnset() {
+	u.setItems = make(map[string]bool)
+}
+
+func TestParseNestedRequestParamsHappyPath(t *testing.T) {
+	testData := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		wanted      *user2
+	}{
+		{
+			name: "dotted keys",
+			url:  "http://localhost:8080/users?page=2&size=10&filter.name=bob",
+			wanted: &user2{
+				pagination: pagination{Page: 2, Size: 10},
+				Filter:     userFilter{Name: "bob"},
+				setItems:   map[string]bool{"page": true, "size": true, "filter": true, "filter.name": true},
+			},
+		},
+		{
+			name: "bracket keys",
+			url:  "http://localhost:8080/users?filter[name]=bob&extra[name]=alice",
+			wanted: &user2{
+				Filter:   userFilter{Name: "bob"},
+				Extra:    &userFilter{Name: "alice"},
+				setItems: map[string]bool{"filter": true, "filter.name": true, "extra": true, "extra.name": true},
+			},
+		},
+		{
+			name:        "json nested objects",
+			url:         "http://localhost:8080/users",
+			contentType: "application/json",
+			body:        `{"page":"3","filter":{"name":"bob","city":"paris"}}`,
+			wanted: &user2{
+				pagination: pagination{Page: 3},
+				Filter:     userFilter{Name: "bob", City: "paris"},
+				setItems:   map[string]bool{"page": true, "filter": true, "filter.name": true, "filter.city": true},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		method := "GET"
+		if test.body != "" {
+			method = "POST"
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Add("Content-Type", test.contentType)
+		}
+		u := &user2{}
+		ok := ParseRequestParams(r, u)
+		assert.True(t, ok, test.name)
+		assert.Equal(t, test.wanted.pagination, u.pagination, test.name)
+		assert.Equal(t, test.wanted.Filter, u.Filter, test.name)
+		assert.Equal(t, test.wanted.Extra, u.Extra, test.name)
+		assert.Equal(t, test.wanted.setItems, u.setItems, test.name)
+	}
+}
+
+func TestParseNestedRequestParamsError(t *testing.T) {
+	testData := []struct {
+		name string
+		url  string
+	}{
+		{
+			name: "missing required nested field",
+			url:  "http://localhost:8080/users?filter.city=paris",
+		},
+		{
+			name: "required nested field inside optional struct",
+			url:  "http://localhost:8080/users?filter.name=bob&extra.city=paris",
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("GET", test.url, strings.NewReader(""))
+		u := &user2{}
 		ok := ParseRequestParams(r, u)
 		assert.False(t, ok, test.name)
 	}
 }
+
+func benchmarkParseRequestParams(b *testing.B, cachePlans bool) {
+	t := reflect.TypeOf(user2{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if !cachePlans {
+			bindPlans.Delete(t)
+		}
+		r, _ := http.NewRequest("GET", "http://localhost:8080/users?page=2&size=10&filter.name=bob&extra[name]=alice", nil)
+		if !ParseRequestParams(r, &user2{}) {
+			b.Fatal("parse request params failed")
+		}
+	}
+}
+
+func BenchmarkParseRequestParams(b *testing.B) {
+	benchmarkParseRequestParams(b, true)
+}
+
+func BenchmarkParseRequestParamsWithoutPlanCache(b *testing.B) {
+	benchmarkParseRequestParams(b, false)
+}
@@ -0,0 +1,37 @@
+package middlewares
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/yikailee/golang/dto"
+)
+
+// Register compiles the binding plan of the DTO type of in, so that a bad
+// default value or an unknown source is reported when the routes are set up
+// rather than when a request is bound.
+func Register(in dto.ValidRequestDTO) error {
+	t := reflect.TypeOf(in)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	_, err := getBindPlan(t)
+	return err
+}
+
+// MustRegister is like Register but panics if a DTO has invalid tags.
+func MustRegister(ins ...dto.ValidRequestDTO) {
+	for _, in := range ins {
+		if err := Register(in); err != nil {
+			panic(fmt.Sprintf("middlewares: register %T: %v", in, err))
+		}
+	}
+}
+
+func knownSource(source string) bool {
+	switch source {
+	case SourcePath, SourceQuery, SourceForm, SourceBody, SourceHeader, SourceCookie, SourceContext, SourceFile:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type defaultUser struct {
+	Page     int64    `dto:"page" default:"1"`
+	Size     int64    `dto:"size" required:"false" default:"20"`
+	Sort     []string `dto:"sort" required:"false" default:"name,age"`
+	Name     string   `dto:"name" required:"false"`
+	setItems map[string]bool
+}
+
+func (u *defaultUser) AlreadySet(dtoName string) bool {
+	_, ok := u.setItems[dtoName]
+	return ok
+}
+
+func (u *defaultUser) MarkSet(dtoName string) {
+	if u.setItems == nil {
+		u.setItems = make(map[string]bool)
+	}
+	u.setItems[dtoName] = true
+}
+
+func (u *defaultUser) MarkAllUnset() {
+	u.setItems = make(map[string]bool)
+}
+
+type badDefaultUser struct {
+	defaultUser
+	Age int64 `dto:"age" default:"ten"`
+}
+
+type badSourceUser struct {
+	defaultUser
+	Filter struct {
+		Age int64 `dto:"age,params"`
+	} `dto:"filter"`
+}
+
+func TestDefaultValues(t *testing.T) {
+	testData := []struct {
+		name   string
+		url    string
+		wanted defaultUser
+	}{
+		{
+			name: "defaults applied",
+			url:  "http://localhost:8080/users",
+			wanted: defaultUser{
+				Page: 1,
+				Size: 20,
+				Sort: []string{"name", "age"},
+			},
+		},
+		{
+			name: "params override defaults",
+			url:  "http://localhost:8080/users?page=3&sort=age&name=bob",
+			wanted: defaultUser{
+				Page:     3,
+				Size:     20,
+				Sort:     []string{"age"},
+				Name:     "bob",
+				setItems: map[string]bool{"page": true, "sort": true, "name": true},
+			},
+		},
+	}
+
+	for _, test := range testData {
+		r, _ := http.NewRequest("GET", test.url, nil)
+		u := &defaultUser{}
+		err := BindRequestParams(r, u)
+		assert.Nil(t, err, test.name)
+		assert.Equal(t, test.wanted, *u, test.name)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	assert.Nil(t, Register(&defaultUser{}), "valid defaults")
+	assert.NotPanics(t, func() { MustRegister(&defaultUser{}, &user1{}) }, "valid dtos")
+	assert.NotNil(t, Register(&badDefaultUser{}), "bad default")
+	assert.NotNil(t, Register(&badSourceUser{}), "bad nested source")
+	assert.Panics(t, func() { MustRegister(&defaultUser{}, &badDefaultUser{}) }, "bad default")
+}
@@ -0,0 +1,144 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrUnknownParam = errors.New("unknown param")
+
+// Strict makes binding fail with ErrUnknownParam when the query, the form or
+// the body has keys that do not match any dto tag. A DTO can also opt in
+// with a blank marker field:
+//
+//	_ struct{} `strict:"true"`
+func Strict() Option {
+	return func(o *parseOptions) {
+		o.strict = true
+	}
+}
+
+// knownParams holds the keys each source may bind, keys of fields without
+// source are stored under "".
+type knownParams map[string]map[string]bool
+
+func (k knownParams) add(key, source string) {
+	if k[source] == nil {
+		k[source] = make(map[string]bool)
+	}
+	k[source][key] = true
+}
+
+// has reports whether key is known in source, or has the "meta." prefix of a
+// known map field.
+func (k knownParams) has(key, source string) bool {
+	if k[""][key] || k[source][key] {
+		return true
+	}
+	for i := strings.IndexByte(key, '.'); i >= 0; {
+		prefix := key[:i+1]
+		if k[""][prefix] || k[source][prefix] {
+			return true
+		}
+		j := strings.IndexByte(key[i+1:], '.')
+		if j < 0 {
+			break
+		}
+		i += j + 1
+	}
+	return false
+}
+
+func newPlanKnownParams(plan *bindPlan) knownParams {
+	known := make(knownParams)
+	var walk func(*bindPlan)
+	walk = func(plan *bindPlan) {
+		for _, fp := range plan.fields {
+			if fp.nested != nil {
+				walk(fp.nested)
+				continue
+			}
+			if fp.recursive != nil {
+				// the keys of recursive fields are known by prefix
+				known.add(fp.key+".", "")
+				continue
+			}
+			known.add(fp.key, fp.source)
+			if fp.maps != nil {
+				known.add(fp.key+".", fp.source)
+			}
+		}
+	}
+	walk(plan)
+	return known
+}
+
+// unknownParams lists the query and form keys that match no key of known, in
+// their dotted form without array indexes ("items[0][name]" is checked as
+// "items.name"), followed by the source they were sent in.
+func unknownParams(src *paramSources, known knownParams) []string {
+	var unknown []string
+	for _, s := range []struct {
+		source string
+		values map[string][]string
+	}{
+		{SourceQuery, src.query},
+		{SourceForm, src.form},
+	} {
+		for k := range s.values {
+			if key := strictKey(k); !known.has(key, s.source) {
+				unknown = append(unknown, fmt.Sprintf("%s (%s)", key, s.source))
+			}
+		}
+	}
+	return unknown
+}
+
+// strictKey strips the empty and numeric segments of a normalized key.
+func strictKey(key string) string {
+	if strings.IndexByte(key, '.') < 0 {
+		return key
+	}
+	segs := strings.Split(key, ".")
+	kept := segs[:0]
+	for _, seg := range segs {
+		if seg != "" && strings.Trim(seg, "0123456789") != "" {
+			kept = append(kept, seg)
+		}
+	}
+	return strings.Join(kept, ".")
+}
+
+// checkStrictParams reports the query, form and body keys that match no
+// field of the plan.
+func checkStrictParams(plan *bindPlan, src *paramSources, body map[string]interface{}) error {
+	unknown := unknownParams(src, plan.known)
+	names, err := unknownJSONFields(plan, body, "")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		unknown = append(unknown, name+" (body)")
+	}
+	return unknownParamError(unknown)
+}
+
+func unknownParamError(unknown []string) error {
+	if len(unknown) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownParam, strings.Join(sortedUnique(unknown), ", "))
+}
+
+func sortedUnique(strs []string) []string {
+	sort.Strings(strs)
+	out := strs[:0]
+	for i, s := range strs {
+		if i == 0 || s != strs[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yikailee/golang/dto"
+)
+
+type strictUser struct {
+	dto.FieldSet
+	_    struct{} `strict:"true"`
+	Name string   `dto:"name" required:"false"`
+	Page int      `dto:"page,query" required:"false"`
+	Tags []string `dto:"tags,form" required:"false"`
+}
+
+func TestStrictParamsHappyPath(t *testing.T) {
+	testData := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		in          dto.ValidRequestDTO
+		opts        []Option
+	}{
+		{
+			name: "marker tag",
+			url:  "http://localhost:8080/users?page=2&name=bob",
+			in:   &strictUser{},
+		},
+		{
+			name:        "form array keys",
+			url:         "http://localhost:8080/users",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "tags[]=a&tags[1]=b",
+			in:          &strictUser{},
+		},
+		{
+			name: "nested keys",
+			url:  "http://localhost:8080/users?page=1&filter[name]=bob&extra.name=alice&extra.city=paris",
+			in:   &user2{},
+			opts: []Option{Strict()},
+		},
+		{
+			name:        "json body",
+			url:         "http://localhost:8080/users",
+			contentType: "application/json",
+			body:        `{"page": 1, "filter": {"name": "bob"}}`,
+			in:          &user2{},
+			opts:        []Option{Strict()},
+		},
+		{
+			name: "not strict",
+			url:  "http://localhost:8080/users?filter.name=bob&pgae=2",
+			in:   &user2{},
+		},
+	}
+
+	for _, test := range testData {
+		method := "GET"
+		if test.body != "" {
+			method = "POST"
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Add("Content-Type", test.contentType)
+		}
+		err := BindRequestParams(r, test.in, test.opts...)
+		assert.Nil(t, err, test.name)
+	}
+}
+
+func TestStrictParamsError(t *testing.T) {
+	testData := []struct {
+		name        string
+		url         string
+		contentType string
+		body        string
+		in          dto.ValidRequestDTO
+		opts        []Option
+		wanted      string
+	}{
+		{
+			name:   "query typo",
+			url:    "http://localhost:8080/users?pgae=2&nmae=bob",
+			in:     &strictUser{},
+			wanted: "unknown param: nmae (query), pgae (query)",
+		},
+		{
+			name:   "form only field in the query",
+			url:    "http://localhost:8080/users?tags=a",
+			in:     &strictUser{},
+			wanted: "unknown param: tags (query)",
+		},
+		{
+			name:        "unknown form key",
+			url:         "http://localhost:8080/users",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "name=bob&page=1",
+			in:          &strictUser{},
+			wanted:      "unknown param: page (form)",
+		},
+		{
+			name:   "unknown nested key",
+			url:    "http://localhost:8080/users?filter[name]=bob&filter[nmae]=bob",
+			in:     &user2{},
+			opts:   []Option{Strict()},
+			wanted: "unknown param: filter.nmae (query)",
+		},
+		{
+			name:        "unknown json keys",
+			url:         "http://localhost:8080/users",
+			contentType: "application/json",
+			body:        `{"nick": "bob", "filter": {"name": "bob", "town": "paris"}}`,
+			in:          &user2{},
+			opts:        []Option{Strict()},
+			wanted:      "unknown param: filter.town (body), nick (body)",
+		},
+	}
+
+	for _, test := range testData {
+		method := "GET"
+		if test.body != "" {
+			method = "POST"
+		}
+		r, _ := http.NewRequest(method, test.url, strings.NewReader(test.body))
+		if test.contentType != "" {
+			r.Header.Add("Content-Type", test.contentType)
+		}
+		err := BindRequestParams(r, test.in, test.opts...)
+		assert.True(t, errors.Is(err, ErrUnknownParam), test.name)
+		if err != nil {
+			assert.Equal(t, test.wanted, err.Error(), test.name)
+		}
+	}
+}
+
+func TestStrictParamsGenerated(t *testing.T) {
+	r, _ := http.NewRequest("GET", "http://localhost:8080/users?age=10&hobby=a", nil)
+	assert.Nil(t, (&genUser1{}).Bind(r, Strict()), "known params")
+
+	r, _ = http.NewRequest("GET", "http://localhost:8080/users?age=10&hoby=a", nil)
+	err := (&genUser1{}).Bind(r, Strict())
+	assert.True(t, errors.Is(err, ErrUnknownParam), "unknown param")
+	assert.Nil(t, (&genUser1{}).Bind(r), "not strict")
+}
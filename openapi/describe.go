@@ -0,0 +1,59 @@
+package openapi
+
+import (
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// OperationOption sets the documentation of an operation.
+type OperationOption func(*Operation)
+
+// Summary sets the summary of the operation.
+func Summary(s string) OperationOption {
+	return func(op *Operation) {
+		op.Summary = s
+	}
+}
+
+// Tags groups the operation under tags.
+func Tags(tags ...string) OperationOption {
+	return func(op *Operation) {
+		op.Tags = append(op.Tags, tags...)
+	}
+}
+
+type description struct {
+	req    interface{}
+	rsp    interface{}
+	opts   []OperationOption
+	hidden bool
+}
+
+var descriptions = struct {
+	sync.RWMutex
+	m map[*mux.Route]*description
+}{m: make(map[*mux.Route]*description)}
+
+// Describe records the request DTO and the response of route, either values
+// or pointers of their types. A nil req documents no params besides the
+// path variables, a nil rsp a 204 No Content response. It returns route.
+func Describe(route *mux.Route, req, rsp interface{}, opts ...OperationOption) *mux.Route {
+	descriptions.Lock()
+	defer descriptions.Unlock()
+	descriptions.m[route] = &description{req: req, rsp: rsp, opts: opts}
+	return route
+}
+
+// hide leaves route out of the generated documents.
+func hide(route *mux.Route) {
+	descriptions.Lock()
+	defer descriptions.Unlock()
+	descriptions.m[route] = &description{hidden: true}
+}
+
+func describedRoute(route *mux.Route) *description {
+	descriptions.RLock()
+	defer descriptions.RUnlock()
+	return descriptions.m[route]
+}
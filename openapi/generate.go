@@ -0,0 +1,244 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/yikailee/golang/dto"
+	"github.com/yikailee/golang/middlewares"
+)
+
+var (
+	ErrNotStruct       = errors.New("request DTO is not a struct")
+	ErrUnknownPathVar  = errors.New("path param not in the route template")
+	ErrIllegalTemplate = errors.New("illegal path template")
+)
+
+// Generate documents the routes of router. The routes described with
+// Describe document their request DTO and response, the other routes only
+// their path variables.
+func Generate(router *mux.Router, info Info) (*Document, error) {
+	doc := &Document{OpenAPI: Version, Info: info, Paths: make(map[string]*PathItem)}
+	s := newSchemas()
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		if route.GetHandler() == nil {
+			return nil
+		}
+		d := describedRoute(route)
+		if d != nil && d.hidden {
+			return nil
+		}
+		tpl, err := route.GetPathTemplate()
+		if err != nil {
+			// routes matching hosts or headers only
+			return nil
+		}
+		path, vars, err := parsePathTemplate(tpl)
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			methods = []string{http.MethodGet}
+		}
+		for _, method := range methods {
+			op, err := s.operation(route, method, vars, d)
+			if err != nil {
+				return fmt.Errorf("%s %s: %w", method, tpl, err)
+			}
+			item := doc.Paths[path]
+			if item == nil {
+				item = &PathItem{}
+				doc.Paths[path] = item
+			}
+			(*item)[strings.ToLower(method)] = op
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(s.components) > 0 {
+		doc.Components = &Components{Schemas: s.components}
+	}
+	return doc, nil
+}
+
+type pathVar struct {
+	name    string
+	pattern string
+}
+
+// parsePathTemplate turns a mux path template ("/users/{id:[0-9]+}") into
+// an OpenAPI path ("/users/{id}") and its variables.
+func parsePathTemplate(tpl string) (string, []pathVar, error) {
+	var (
+		path strings.Builder
+		vars []pathVar
+	)
+	for i := 0; i < len(tpl); i++ {
+		if tpl[i] != '{' {
+			path.WriteByte(tpl[i])
+			continue
+		}
+		depth, end := 0, -1
+		for j := i; j < len(tpl) && end < 0; j++ {
+			switch tpl[j] {
+			case '{':
+				depth++
+			case '}':
+				if depth--; depth == 0 {
+					end = j
+				}
+			}
+		}
+		if end < 0 {
+			return "", nil, fmt.Errorf("%w: %s", ErrIllegalTemplate, tpl)
+		}
+		v := pathVar{name: tpl[i+1 : end]}
+		if k := strings.IndexByte(v.name, ':'); k >= 0 {
+			v.name, v.pattern = v.name[:k], "^"+v.name[k+1:]+"$"
+		}
+		vars = append(vars, v)
+		path.WriteString("{" + v.name + "}")
+		i = end
+	}
+	return path.String(), vars, nil
+}
+
+func (s *schemas) operation(route *mux.Route, method string, vars []pathVar, d *description) (*Operation, error) {
+	op := &Operation{OperationID: route.GetName(), Responses: make(map[string]*Response)}
+	pathParams := make(map[string]*Parameter, len(vars))
+	for _, v := range vars {
+		p := &Parameter{Name: v.name, In: "path", Required: true, Schema: &Schema{Type: "string", Pattern: v.pattern}}
+		pathParams[v.name] = p
+		op.Parameters = append(op.Parameters, p)
+	}
+	if d == nil {
+		op.Responses["200"] = &Response{Description: "OK"}
+		return op, nil
+	}
+	for _, opt := range d.opts {
+		opt(op)
+	}
+
+	if d.req != nil {
+		t := reflect.TypeOf(d.req)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%w: %T", ErrNotStruct, d.req)
+		}
+		if err := s.requestParams(op, method, pathParams, s.dtoFields(t)); err != nil {
+			return nil, err
+		}
+		op.Responses["400"] = &Response{
+			Description: "Bad Request",
+			Content:     jsonContent(s.response(reflect.TypeOf(dto.GeneralRsp{}))),
+		}
+	}
+	if d.rsp == nil {
+		op.Responses["204"] = &Response{Description: "No Content"}
+	} else {
+		op.Responses["200"] = &Response{Description: "OK", Content: jsonContent(s.response(reflect.TypeOf(d.rsp)))}
+	}
+	return op, nil
+}
+
+// requestParams documents the fields of a request DTO: the path, query,
+// header and cookie params, and the body of the methods which have one.
+func (s *schemas) requestParams(op *Operation, method string, pathParams map[string]*Parameter, fields []dtoField) error {
+	hasBody := method == http.MethodPost || method == http.MethodPut || method == http.MethodPatch
+	var jsonFields, formFields []dtoField
+	hasForm, hasFile := false, false
+	for _, f := range fields {
+		source := f.source
+		if source == "" {
+			switch {
+			case pathParams[f.name] != nil:
+				source = middlewares.SourcePath
+			case hasBody:
+				jsonFields = append(jsonFields, f)
+				formFields = append(formFields, f)
+				continue
+			default:
+				source = middlewares.SourceQuery
+			}
+		}
+
+		switch source {
+		case middlewares.SourcePath:
+			p := pathParams[f.name]
+			if p == nil {
+				return fmt.Errorf("%w: %s", ErrUnknownPathVar, f.name)
+			}
+			if f.schema.Pattern == "" && f.schema.Type == "string" {
+				f.schema.Pattern = p.Schema.Pattern
+			}
+			p.Schema = f.schema
+		case middlewares.SourceQuery, middlewares.SourceHeader, middlewares.SourceCookie:
+			op.Parameters = append(op.Parameters, queryParam(f, source))
+		case middlewares.SourceBody:
+			jsonFields = append(jsonFields, f)
+		case middlewares.SourceForm:
+			formFields = append(formFields, f)
+			hasForm = true
+		case middlewares.SourceFile:
+			formFields = append(formFields, f)
+			hasForm, hasFile = true, true
+		}
+	}
+
+	content := make(map[string]*MediaType)
+	required := false
+	if len(jsonFields) > 0 {
+		obj := dtoObject(jsonFields)
+		content["application/json"] = &MediaType{Schema: obj}
+		required = len(obj.Required) > 0
+	}
+	if hasForm {
+		obj := dtoObject(formFields)
+		mediaType := "application/x-www-form-urlencoded"
+		if hasFile {
+			mediaType = "multipart/form-data"
+		}
+		content[mediaType] = &MediaType{Schema: obj}
+		required = required || len(obj.Required) > 0
+	}
+	if len(content) > 0 {
+		op.RequestBody = &RequestBody{Required: required, Content: content}
+	}
+	return nil
+}
+
+// collectionStyles maps the collection tags to the OpenAPI style of query
+// params, which are not exploded.
+var collectionStyles = map[string]string{
+	middlewares.CollectionCSV:   "form",
+	middlewares.CollectionSSV:   "spaceDelimited",
+	middlewares.CollectionPipes: "pipeDelimited",
+}
+
+func queryParam(f dtoField, in string) *Parameter {
+	p := &Parameter{Name: f.name, In: in, Required: f.required, Schema: f.schema}
+	if in != middlewares.SourceQuery {
+		return p
+	}
+	explode := false
+	if style, ok := collectionStyles[f.collection]; ok {
+		p.Style, p.Explode = style, &explode
+	} else if f.object {
+		explode = true
+		p.Style, p.Explode = "deepObject", &explode
+	}
+	return p
+}
+
+func jsonContent(schema *Schema) map[string]*MediaType {
+	return map[string]*MediaType{"application/json": {Schema: schema}}
+}
@@ -0,0 +1,222 @@
+package openapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/yikailee/golang/middlewares"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+type user struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	Friends   []*user   `json:"friends,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	internal  string
+}
+
+type pagination struct {
+	Page int64 `dto:"page" required:"false" validate:"min=1"`
+	Size int64 `dto:"size" default:"20" validate:"min=1,max=100"`
+}
+
+type userFilter struct {
+	Name string `dto:"name" required:"false"`
+	City string `dto:"city" required:"false"`
+}
+
+type getUserReq struct {
+	ID     int64    `dto:"id,path"`
+	Fields []string `dto:"fields,query" required:"false" collection:"csv"`
+	Tenant string   `dto:"X-Tenant,header" required:"false" validate:"uuid"`
+}
+
+type listUsersReq struct {
+	pagination
+	Filter userFilter        `dto:"filter" required:"false"`
+	Sort   string            `dto:"sort" default:"name" validate:"oneof=name age"`
+	Meta   map[string]string `dto:"meta" required:"false"`
+	Caller string            `dto:"caller,context" required:"false"`
+}
+
+type createUserReq struct {
+	_     struct{} `strict:"true"`
+	Name  string   `dto:"name" validate:"min=1,max=32"`
+	Email string   `dto:"email,body" validate:"email"`
+	Age   int      `dto:"age" required:"false" validate:"min=0,max=150"`
+	Tags  []string `dto:"tags,body" required:"false" validate:"max=5,oneof=admin staff"`
+}
+
+type uploadAvatarReq struct {
+	ID      int64                 `dto:"id,path"`
+	Avatar  *multipart.FileHeader `dto:"avatar"`
+	Caption string                `dto:"caption,form" required:"false"`
+}
+
+type categoryReq struct {
+	Name     string        `dto:"name"`
+	Parent   *categoryReq  `dto:"parent" required:"false"`
+	Children []categoryReq `dto:"children" required:"false"`
+}
+
+func noop(w http.ResponseWriter, r *http.Request) {}
+
+func newTestRouter() *mux.Router {
+	r := mux.NewRouter()
+	Describe(r.HandleFunc("/users/{id:[0-9]+}", noop).Methods("GET").Name("getUser"), &getUserReq{}, user{},
+		Summary("Get a user"), Tags("users"))
+	Describe(r.HandleFunc("/users", noop).Methods("GET").Name("listUsers"), listUsersReq{}, []user{}, Tags("users"))
+	Describe(r.Handle("/users", middlewares.Handle(func(ctx context.Context, in *createUserReq) (any, error) {
+		return nil, nil
+	})).Methods("POST").Name("createUser"), &createUserReq{}, &user{}, Tags("users"))
+	Describe(r.HandleFunc("/users/{id}/avatar", noop).Methods("PUT"), &uploadAvatarReq{}, nil)
+	r.HandleFunc("/health", noop)
+	Mount(r, "/openapi.json", Info{Title: "users", Version: "1.0.0"})
+	return r
+}
+
+func TestGenerateGolden(t *testing.T) {
+	r := newTestRouter()
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code, "status code")
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"), "content type")
+
+	golden := filepath.Join("testdata", "users.golden.json")
+	if *update {
+		if err := ioutil.WriteFile(golden, append(w.Body.Bytes(), '\n'), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	wanted, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.JSONEq(t, string(wanted), w.Body.String(), "golden document")
+}
+
+func TestGenerateError(t *testing.T) {
+	testData := []struct {
+		name   string
+		route  func(r *mux.Router)
+		wanted error
+	}{
+		{
+			name: "path param not in template",
+			route: func(r *mux.Router) {
+				Describe(r.HandleFunc("/users", noop), &getUserReq{}, nil)
+			},
+			wanted: ErrUnknownPathVar,
+		},
+		{
+			name: "request DTO not a struct",
+			route: func(r *mux.Router) {
+				Describe(r.HandleFunc("/users", noop), "name", nil)
+			},
+			wanted: ErrNotStruct,
+		},
+	}
+
+	for _, test := range testData {
+		r := mux.NewRouter()
+		test.route(r)
+		_, err := Generate(r, Info{Title: "users", Version: "1.0.0"})
+		assert.True(t, errors.Is(err, test.wanted), test.name)
+	}
+}
+
+func TestParsePathTemplate(t *testing.T) {
+	path, vars, err := parsePathTemplate("/orgs/{org}/users/{id:[0-9]{3}}")
+	assert.Nil(t, err, "parse")
+	assert.Equal(t, "/orgs/{org}/users/{id}", path, "path")
+	assert.Equal(t, []pathVar{{name: "org"}, {name: "id", pattern: "^[0-9]{3}$"}}, vars, "vars")
+
+	_, _, err = parsePathTemplate("/users/{id")
+	assert.True(t, errors.Is(err, ErrIllegalTemplate), "unterminated variable")
+}
+
+func TestApplyValidate(t *testing.T) {
+	min, max, n := 1.0, 10.0, 3
+	testData := []struct {
+		name     string
+		schema   *Schema
+		tag      string
+		wanted   *Schema
+		required bool
+	}{
+		{
+			name:   "number bounds",
+			schema: &Schema{Type: "integer"},
+			tag:    "min=1,max=10",
+			wanted: &Schema{Type: "integer", Minimum: &min, Maximum: &max},
+		},
+		{
+			name:     "string length and required",
+			schema:   &Schema{Type: "string"},
+			tag:      "required,len=3",
+			wanted:   &Schema{Type: "string", MinLength: &n, MaxLength: &n},
+			required: true,
+		},
+		{
+			name:   "enum items",
+			schema: &Schema{Type: "array", Items: &Schema{Type: "integer"}},
+			tag:    "oneof=1 2",
+			wanted: &Schema{Type: "array", Items: &Schema{Type: "integer", Enum: []interface{}{int64(1), int64(2)}}},
+		},
+		{
+			name:   "format and unknown rules",
+			schema: &Schema{Type: "string"},
+			tag:    "url,alphanum",
+			wanted: &Schema{Type: "string", Format: "uri"},
+		},
+	}
+
+	for _, test := range testData {
+		required := applyValidate(test.schema, test.tag)
+		assert.Equal(t, test.wanted, test.schema, test.name)
+		assert.Equal(t, test.required, required, test.name)
+	}
+}
+
+func TestGenerateMarshal(t *testing.T) {
+	doc, err := Generate(newTestRouter(), Info{Title: "users", Version: "1.0.0"})
+	assert.Nil(t, err, "generate")
+	_, err = json.Marshal(doc)
+	assert.Nil(t, err, "marshal")
+	assert.Nil(t, (*doc.Paths["/health"])["get"].RequestBody, "undescribed route")
+	assert.Nil(t, doc.Paths["/openapi.json"], "document route")
+}
+
+func TestGenerateRecursiveDTO(t *testing.T) {
+	r := mux.NewRouter()
+	Describe(r.HandleFunc("/categories", noop).Methods("POST"), &categoryReq{}, nil)
+	doc, err := Generate(r, Info{Title: "categories", Version: "1.0.0"})
+	if !assert.Nil(t, err, "generate") {
+		return
+	}
+	ref := "#/components/schemas/categoryReqRequest"
+	body := (*doc.Paths["/categories"])["post"].RequestBody.Content["application/json"].Schema
+	assert.Equal(t, ref, body.Properties["parent"].Ref, "parent reference")
+	assert.Equal(t, ref, body.Properties["children"].Items.Ref, "children reference")
+	component := doc.Components.Schemas["categoryReqRequest"]
+	if assert.NotNil(t, component, "component") {
+		assert.Equal(t, ref, component.Properties["parent"].Ref, "component parent reference")
+		assert.Equal(t, []string{"name"}, component.Required, "component required")
+	}
+	_, err = json.Marshal(doc)
+	assert.Nil(t, err, "marshal")
+}
@@ -0,0 +1,41 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+// Handler serves the document of router as JSON. It is generated on the
+// first request, so that it documents the routes added after Handler.
+func Handler(router *mux.Router, info Info) http.Handler {
+	var (
+		once sync.Once
+		doc  []byte
+		err  error
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			var d *Document
+			if d, err = Generate(router, info); err == nil {
+				doc, err = json.MarshalIndent(d, "", "  ")
+			}
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(doc)
+	})
+}
+
+// Mount serves the document of router at path, which is left out of the
+// document.
+func Mount(router *mux.Router, path string, info Info) *mux.Route {
+	route := router.Handle(path, Handler(router, info)).Methods(http.MethodGet)
+	hide(route)
+	return route
+}
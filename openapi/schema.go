@@ -0,0 +1,429 @@
+package openapi
+
+import (
+	"mime/multipart"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yikailee/golang/middlewares"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+	bytesType      = reflect.TypeOf([]byte(nil))
+)
+
+// schemas builds the schemas of a document, the named struct types of the
+// responses and the recursive struct types of the request DTOs are added to
+// the components and referenced.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+	// visiting holds the request DTO structs whose fields are being
+	// described, recursive the ones found nested in themselves
+	visiting  map[reflect.Type]bool
+	recursive map[reflect.Type]bool
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: make(map[string]*Schema),
+		names:      make(map[reflect.Type]string),
+		visiting:   make(map[reflect.Type]bool),
+		recursive:  make(map[reflect.Type]bool),
+	}
+}
+
+func basicSchema(t reflect.Type) *Schema {
+	switch t {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case bytesType:
+		return &Schema{Type: "string", Format: "byte"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	}
+	return nil
+}
+
+// response returns the schema of t encoded by encoding/json.
+func (s *schemas) response(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if b := basicSchema(t); b != nil {
+		return b
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.response(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.response(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.jsonObject(t)
+		}
+		name := s.name(t)
+		if _, ok := s.components[name]; !ok {
+			// added before its fields for recursive types
+			obj := &Schema{}
+			s.components[name] = obj
+			*obj = *s.jsonObject(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return &Schema{}
+}
+
+// name returns the component name of t, qualified by its package when
+// another type has the same name.
+func (s *schemas) name(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	for other, n := range s.names {
+		if n == name && other != t {
+			name = path.Base(t.PkgPath()) + "." + name
+			break
+		}
+	}
+	s.names[t] = name
+	return name
+}
+
+func (s *schemas) jsonObject(t reflect.Type) *Schema {
+	obj := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	s.addJSONFields(obj, t)
+	return obj
+}
+
+func (s *schemas) addJSONFields(obj *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			s.addJSONFields(obj, ft)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		schema := s.response(f.Type)
+		if hasOption(opts, "string") && schema.Ref == "" {
+			schema = &Schema{Type: "string"}
+		}
+		obj.Properties[name] = schema
+		if !hasOption(opts, "omitempty") {
+			obj.Required = append(obj.Required, name)
+		}
+	}
+}
+
+func hasOption(opts, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// dtoField is a dto tagged field of a request DTO, fields of embedded
+// structs without dto tag are promoted.
+type dtoField struct {
+	name       string
+	source     string
+	required   bool
+	collection string
+	object     bool // nested struct or map
+	schema     *Schema
+}
+
+func (s *schemas) dtoFields(t reflect.Type) []dtoField {
+	if s.visiting[t] {
+		// an embedded struct pointer of its own type
+		return nil
+	}
+	s.visiting[t] = true
+	fields := s.addDTOFields(nil, t)
+	delete(s.visiting, t)
+	if s.recursive[t] {
+		s.components[s.dtoName(t)] = dtoObject(fields)
+	}
+	return fields
+}
+
+// dtoName returns the component name of the recursive request DTO t, which
+// differs from the response schema of t.
+func (s *schemas) dtoName(t reflect.Type) string {
+	return s.name(t) + "Request"
+}
+
+func (s *schemas) addDTOFields(fields []dtoField, t reflect.Type) []dtoField {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Name == "_" {
+			continue
+		}
+		tag := f.Tag.Get("dto")
+		name, source := tag, ""
+		if i := strings.IndexByte(tag, ','); i >= 0 {
+			name, source = tag[:i], strings.TrimSpace(tag[i+1:])
+		}
+		if name == "" {
+			if ft := f.Type; f.Anonymous && ft.Kind() == reflect.Struct {
+				fields = s.addDTOFields(fields, ft)
+			} else if f.Anonymous && ft.Kind() == reflect.Ptr && ft.Elem().Kind() == reflect.Struct {
+				fields = append(fields, s.dtoFields(ft.Elem())...)
+			}
+			continue
+		}
+
+		schema := s.dtoSchema(f.Type)
+		_, hasDefault := f.Tag.Lookup("default")
+		required := f.Tag.Get("required") != "false"
+		if applyValidate(schema, f.Tag.Get("validate")) {
+			required = true
+		}
+		if def, ok := f.Tag.Lookup("default"); ok {
+			schema.Default = typedDefault(f.Type, def)
+		}
+		if source == "" && isFile(f.Type) {
+			source = middlewares.SourceFile
+		}
+		fields = append(fields, dtoField{
+			name:       name,
+			source:     source,
+			required:   required && !hasDefault,
+			collection: f.Tag.Get("collection"),
+			object:     schema.Type == "object" || schema.Ref != "",
+			schema:     schema,
+		})
+	}
+	return fields
+}
+
+func isFile(t reflect.Type) bool {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t == fileHeaderType
+}
+
+// dtoSchema returns the schema of a request DTO field of type t, nested
+// structs are described by their dto tags. Recursive structs are referenced.
+func (s *schemas) dtoSchema(t reflect.Type) *Schema {
+	if t == fileHeaderType {
+		return &Schema{Type: "string", Format: "binary"}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if b := basicSchema(t); b != nil {
+		return b
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		return &Schema{Type: "array", Items: s.dtoSchema(t.Elem())}
+	case reflect.Array:
+		n := t.Len()
+		return &Schema{Type: "array", Items: s.dtoSchema(t.Elem()), MinItems: &n, MaxItems: &n}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.dtoSchema(t.Elem())}
+	case reflect.Struct:
+		if s.visiting[t] {
+			s.recursive[t] = true
+		}
+		if !s.recursive[t] {
+			fields := s.dtoFields(t)
+			if !s.recursive[t] {
+				return dtoObject(fields)
+			}
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.dtoName(t)}
+	}
+	return &Schema{}
+}
+
+func dtoObject(fields []dtoField) *Schema {
+	obj := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range fields {
+		if f.source == middlewares.SourceContext {
+			continue
+		}
+		obj.Properties[f.name] = f.schema
+		if f.required {
+			obj.Required = append(obj.Required, f.name)
+		}
+	}
+	return obj
+}
+
+// typedDefault converts the default tag of a field of type t to the JSON
+// value of the schema default, slices take a comma separated list.
+func typedDefault(t reflect.Type, def string) interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		var values []interface{}
+		for _, d := range strings.Split(def, ",") {
+			values = append(values, scalarValue(t.Elem().Kind(), d))
+		}
+		return values
+	}
+	return scalarValue(t.Kind(), def)
+}
+
+func scalarValue(k reflect.Kind, s string) interface{} {
+	switch k {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, err := strconv.ParseUint(s, 10, 64); err == nil {
+			return u
+		}
+	case reflect.Float32, reflect.Float64:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// validateFormats maps the format rules of validate tags to schema formats.
+var validateFormats = map[string]string{
+	"email":    "email",
+	"uuid":     "uuid",
+	"url":      "uri",
+	"uri":      "uri",
+	"hostname": "hostname",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"datetime": "date-time",
+}
+
+// applyValidate adds the constraints of a validate tag
+// (validate:"min=1,max=32,oneof=a b") to schema, and reports whether it has
+// the required rule. min, max and len bound numbers, the length of strings or
+// the items of arrays.
+func applyValidate(schema *Schema, tag string) (required bool) {
+	if tag == "" {
+		return false
+	}
+	for _, rule := range strings.Split(tag, ",") {
+		key, value := rule, ""
+		if i := strings.IndexByte(rule, '='); i >= 0 {
+			key, value = rule[:i], rule[i+1:]
+		}
+		switch key {
+		case "required":
+			required = true
+		case "min", "gte":
+			setBound(schema, value, true)
+		case "max", "lte":
+			setBound(schema, value, false)
+		case "len":
+			setBound(schema, value, true)
+			setBound(schema, value, false)
+		case "oneof":
+			target := schema
+			if schema.Type == "array" && schema.Items != nil {
+				target = schema.Items
+			}
+			kind := schemaKind(target)
+			for _, v := range strings.Fields(value) {
+				target.Enum = append(target.Enum, scalarValue(kind, v))
+			}
+		default:
+			if format, ok := validateFormats[key]; ok {
+				schema.Format = format
+			}
+		}
+	}
+	return required
+}
+
+func setBound(schema *Schema, value string, min bool) {
+	switch schema.Type {
+	case "integer", "number":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return
+		}
+		if min {
+			schema.Minimum = &f
+		} else {
+			schema.Maximum = &f
+		}
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		if min {
+			schema.MinLength = &n
+		} else {
+			schema.MaxLength = &n
+		}
+	case "array":
+		if min {
+			schema.MinItems = &n
+		} else {
+			schema.MaxItems = &n
+		}
+	}
+}
+
+func schemaKind(schema *Schema) reflect.Kind {
+	switch schema.Type {
+	case "integer":
+		return reflect.Int64
+	case "number":
+		return reflect.Float64
+	case "boolean":
+		return reflect.Bool
+	}
+	return reflect.String
+}